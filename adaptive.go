@@ -0,0 +1,212 @@
+package ray
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"sync"
+)
+
+// DefaultAdaptiveDepth is the max number of subdivisions of a pixel by
+// adaptive antialiasing, up to (2^n+1)² rays per pixel.
+var DefaultAdaptiveDepth = 2
+
+// adaptive is the state of the adaptive antialiasing. The first pass
+// traces a ray through the top left corner of each pixel, so the
+// corners of a pixel are those of its right and bottom neighbours.
+type adaptive struct {
+	w, h    int
+	corners []corner       // first pass, per pixel
+	depth   []uint8        // subdivisions of the refined pixels
+	pending sync.WaitGroup // first pass pixels sent, not drawn yet
+}
+
+// corner is the result of a ray through a corner of a (sub)pixel
+type corner struct {
+	c    FloatColor
+	surf *Surface
+}
+
+func newAdaptive(w, h int) *adaptive {
+	return &adaptive{
+		w:       w,
+		h:       h,
+		corners: make([]corner, w*h),
+		depth:   make([]uint8, w*h),
+	}
+}
+
+// draw records a traced pixel, called by the draw worker
+func (a *adaptive) draw(p pixel) {
+	i := p.y*a.w + p.x
+	if p.refine {
+		a.depth[i] = uint8(p.depth)
+		return
+	}
+	a.corners[i] = corner{c: p.c, surf: p.surf}
+	a.pending.Done()
+}
+
+// skip accounts for the first pass pixels of a batch not traced
+func (a *adaptive) skip(b []pixel) {
+	for _, p := range b {
+		if !p.refine {
+			a.pending.Done()
+		}
+	}
+}
+
+// wait waits for all the pixels of the first pass to be drawn, and
+// returns false if ctx was cancelled before.
+func (a *adaptive) wait(ctx context.Context) bool {
+	drawn := make(chan struct{})
+	go func() {
+		a.pending.Wait()
+		close(drawn)
+	}()
+	select {
+	case <-drawn:
+		return ctx.Err() == nil
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// corner returns the first pass corner of pixel x, y, clamped to the
+// image. Only valid once the first pass is drawn.
+func (a *adaptive) corner(x, y int) corner {
+	return a.corners[clampIndex(y, a.h)*a.w+clampIndex(x, a.w)]
+}
+
+// contrasted returns true if the corners hit different surfaces, or
+// differ by more than the threshold.
+func (s *Scene) contrasted(k [4]corner) bool {
+	min, max := k[0].c, k[0].c
+	for _, c := range k[1:] {
+		if c.surf != k[0].surf {
+			return true
+		}
+		min.R, max.R = math.Min(min.R, c.c.R), math.Max(max.R, c.c.R)
+		min.G, max.G = math.Min(min.G, c.c.G), math.Max(max.G, c.c.G)
+		min.B, max.B = math.Min(min.B, c.c.B), math.Max(max.B, c.c.B)
+	}
+	t := s.AdaptiveThreshold
+	return max.R-min.R > t || max.G-min.G > t || max.B-min.B > t
+}
+
+// refineTracingBatch sends the pixels to refine, once the first pass is
+// drawn.
+func (s *Scene) refineTracingBatch(ctx context.Context) {
+	a := s.adaptive
+	pb := newPixelBatch(s.traceChan, 16)
+	for y := 0; y < a.h; y++ {
+		if ctx.Err() != nil {
+			break
+		}
+		for x := 0; x < a.w; x++ {
+			k := [4]corner{a.corner(x, y), a.corner(x+1, y), a.corner(x, y+1), a.corner(x+1, y+1)}
+			if s.contrasted(k) {
+				pb.add(pixel{x: x, y: y, w: 1, h: 1, refine: true})
+			}
+		}
+	}
+	pb.flush()
+}
+
+// traceCorner traces the ray through x, y in image coords, for pixel
+// px, py.
+func (s *Scene) traceCorner(px, py int, x, y float64) corner {
+	r := s.cam.BuildRayAt(x, y)
+	r.x, r.y = px, py
+	r.Normalize()
+	hit := s.findIntersection(r)
+	if hit == nil {
+		return corner{c: s.Background()}
+	}
+	// before shade, which may replace a textured surface
+	surf := hit.Surface
+	return corner{c: s.shade(r, hit, 0), surf: surf}
+}
+
+// refinePixel returns the color of the pixel x, y, subdivided where its
+// corners contrast, and the depth of the subdivision.
+func (s *Scene) refinePixel(x, y int) (FloatColor, int) {
+	a := s.adaptive
+	k := [4]corner{a.corner(x, y), a.corner(x+1, y), a.corner(x, y+1), a.corner(x+1, y+1)}
+	// trace the corners past the right and bottom borders
+	fx, fy := float64(x), float64(y)
+	if x+1 == a.w {
+		k[1] = s.traceCorner(x, y, fx+1, fy)
+	}
+	if y+1 == a.h {
+		k[2] = s.traceCorner(x, y, fx, fy+1)
+	}
+	if x+1 == a.w || y+1 == a.h {
+		k[3] = s.traceCorner(x, y, fx+1, fy+1)
+	}
+	max := s.AdaptiveDepth
+	if max <= 0 {
+		max = DefaultAdaptiveDepth
+	}
+	return s.subdivide(x, y, fx, fy, 1, k, 0, max)
+}
+
+// subdivide returns the average color of the square of given top left
+// corner and size, of corners k (top left, top right, bottom left and
+// bottom right), splitting it in 4 while they contrast, and the depth of
+// the deepest split.
+func (s *Scene) subdivide(px, py int, x, y, size float64, k [4]corner, depth, max int) (FloatColor, int) {
+	if depth == max || !s.contrasted(k) {
+		var c FloatColor
+		for _, kc := range k {
+			c.Add(kc.c)
+		}
+		return c.MulF(.25), depth
+	}
+	h := size / 2
+	top, left := s.traceCorner(px, py, x+h, y), s.traceCorner(px, py, x, y+h)
+	mid := s.traceCorner(px, py, x+h, y+h)
+	right, bottom := s.traceCorner(px, py, x+size, y+h), s.traceCorner(px, py, x+h, y+size)
+	quads := [4]struct {
+		x, y float64
+		k    [4]corner
+	}{
+		{x, y, [4]corner{k[0], top, left, mid}},
+		{x + h, y, [4]corner{top, k[1], mid, right}},
+		{x, y + h, [4]corner{left, mid, k[2], bottom}},
+		{x + h, y + h, [4]corner{mid, right, bottom, k[3]}},
+	}
+	var c FloatColor
+	deepest := depth + 1
+	for _, q := range quads {
+		qc, d := s.subdivide(px, py, q.x, q.y, h, q.k, depth+1, max)
+		c.Add(qc)
+		if d > deepest {
+			deepest = d
+		}
+	}
+	return c.MulF(.25), deepest
+}
+
+// RefinedImage returns the pixels refined by the last adaptive
+// antialiasing rendering, once finished, to tune AdaptiveThreshold: they
+// are brighter with the depth of their subdivision, the others black.
+// It is nil without adaptive antialiasing.
+func (s *Scene) RefinedImage() *image.Gray {
+	a := s.adaptive
+	if a == nil {
+		return nil
+	}
+	max := s.AdaptiveDepth
+	if max <= 0 {
+		max = DefaultAdaptiveDepth
+	}
+	img := image.NewGray(image.Rect(0, 0, a.w, a.h))
+	for i, d := range a.depth {
+		if d > 0 {
+			img.SetGray(i%a.w, i/a.w, color.Gray{Y: uint8(64 + 191*int(d)/max)})
+		}
+	}
+	return img
+}
@@ -0,0 +1,31 @@
+package ray
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestRenderAdaptiveOddSize checks that an adaptive rendering finishes
+// when the pixel count is not a multiple of the batch size.
+func TestRenderAdaptiveOddSize(t *testing.T) {
+	cam := NewCamera(22, 16, 9, 65)
+	if n := cam.Width * cam.Height; n%16 == 0 {
+		t.Fatalf("%d pixels, a multiple of 16", n)
+	}
+	s := NewScene(cam)
+	s.Workers = 2
+	s.AdaptiveThreshold = .1
+	s.AddLights(NewPointLight(FloatColor{R: 1, G: 1, B: 1}).Translate(-10, 20, 20))
+	s.AddObjects(NewSphere().Translate(0, 0, -10))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	img, err := s.Render(ctx)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if img.Bounds().Dx() != cam.Width || img.Bounds().Dy() != cam.Height {
+		t.Fatalf("image of %v", img.Bounds())
+	}
+}
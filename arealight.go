@@ -0,0 +1,215 @@
+package ray
+
+import "math"
+
+// AreaLight is a light with an extent. A point sees it through several
+// shadow rays, to sample points spread over it, so it lights partly
+// hidden points with the visible fraction of the light, making soft
+// shadows. Like PointLight, it lights all directions.
+type AreaLight interface {
+	Light
+	// Samples returns the number of shadow rays to the light
+	Samples() int
+	// RayToSample returns the ray from pt to the point of the light
+	// selected by u, v in [0,1).
+	RayToSample(pt Point3, u, v float64) Ray
+}
+
+// DefaultLightSamples is the number of shadow rays of a new area light
+var DefaultLightSamples = 16
+
+// areaLight holds what is common to area lights
+type areaLight struct {
+	Transform
+	c           FloatColor
+	sun         bool
+	samples     int
+	Attenuation Attenuation
+}
+
+func newAreaLight(c FloatColor) areaLight {
+	return areaLight{
+		Transform:   IDTransform,
+		c:           c,
+		samples:     DefaultLightSamples,
+		Attenuation: DefaultAttenuation,
+	}
+}
+
+// RayToLight returns the ray to the center of the light
+func (a *areaLight) RayToLight(pt Point3) Ray {
+	return NewRay(pt, a.PointToGlobal(Origin))
+}
+
+// Color ...
+func (a *areaLight) Color(r Ray) FloatColor {
+	return a.c
+}
+
+// Sun ...
+func (a *areaLight) Sun() bool {
+	return a.sun
+}
+
+// SetSun ...
+func (a *areaLight) SetSun(sun bool) {
+	a.sun = sun
+}
+
+func (a *areaLight) attenuation() *Attenuation {
+	return &a.Attenuation
+}
+
+// Samples ...
+func (a *areaLight) Samples() int {
+	return a.samples
+}
+
+// SetSamples sets the number of shadow rays, the more the smoother the
+// shadows. Square numbers are spread the most evenly over the light.
+func (a *areaLight) SetSamples(n int) {
+	if n < 1 {
+		n = 1
+	}
+	a.samples = n
+}
+
+// RectLight is a rectangle light of side 2, centered on origin, in the
+// xz plane. It is sized and placed by its transform.
+type RectLight struct {
+	areaLight
+}
+
+// NewRectLight creates a rectangle light of color c
+func NewRectLight(c FloatColor) *RectLight {
+	return &RectLight{newAreaLight(c)}
+}
+
+// RayToSample implements AreaLight
+func (l *RectLight) RayToSample(pt Point3, u, v float64) Ray {
+	return NewRay(pt, l.PointToGlobal(Point3{2*u - 1, 0, 2*v - 1}))
+}
+
+// Translate applies a translation to the light
+func (l *RectLight) Translate(x, y, z float64) *RectLight {
+	l.Transform.Translate(x, y, z)
+	return l
+}
+
+// RotateX applies a rotation around x-axis to the light
+func (l *RectLight) RotateX(x float64) *RectLight {
+	l.Transform.RotateX(x)
+	return l
+}
+
+// RotateY applies a rotation around y-axis to the light
+func (l *RectLight) RotateY(y float64) *RectLight {
+	l.Transform.RotateY(y)
+	return l
+}
+
+// RotateZ applies a rotation around z-axis to the light
+func (l *RectLight) RotateZ(z float64) *RectLight {
+	l.Transform.RotateZ(z)
+	return l
+}
+
+// Scale applies a scaling transform to the light
+func (l *RectLight) Scale(x, y, z float64) *RectLight {
+	l.Transform.Scale(x, y, z)
+	return l
+}
+
+// DiskLight is a disk light of radius 1, centered on origin, in the
+// xz plane. It is sized and placed by its transform.
+type DiskLight struct {
+	areaLight
+}
+
+// NewDiskLight creates a disk light of color c
+func NewDiskLight(c FloatColor) *DiskLight {
+	return &DiskLight{newAreaLight(c)}
+}
+
+// RayToSample implements AreaLight
+func (l *DiskLight) RayToSample(pt Point3, u, v float64) Ray {
+	x, z := concentricDisk(u, v)
+	return NewRay(pt, l.PointToGlobal(Point3{x, 0, z}))
+}
+
+// Translate applies a translation to the light
+func (l *DiskLight) Translate(x, y, z float64) *DiskLight {
+	l.Transform.Translate(x, y, z)
+	return l
+}
+
+// RotateX applies a rotation around x-axis to the light
+func (l *DiskLight) RotateX(x float64) *DiskLight {
+	l.Transform.RotateX(x)
+	return l
+}
+
+// RotateY applies a rotation around y-axis to the light
+func (l *DiskLight) RotateY(y float64) *DiskLight {
+	l.Transform.RotateY(y)
+	return l
+}
+
+// RotateZ applies a rotation around z-axis to the light
+func (l *DiskLight) RotateZ(z float64) *DiskLight {
+	l.Transform.RotateZ(z)
+	return l
+}
+
+// Scale applies a scaling transform to the light
+func (l *DiskLight) Scale(x, y, z float64) *DiskLight {
+	l.Transform.Scale(x, y, z)
+	return l
+}
+
+// SphereLight is a sphere light of radius 1, centered on origin. It is
+// sized and placed by its transform.
+type SphereLight struct {
+	areaLight
+}
+
+// NewSphereLight creates a sphere light of color c
+func NewSphereLight(c FloatColor) *SphereLight {
+	return &SphereLight{newAreaLight(c)}
+}
+
+// RayToSample implements AreaLight. The samples are on the half of the
+// sphere facing pt: a disk of the sphere silhouette, seen from pt, is
+// projected on the sphere.
+func (l *SphereLight) RayToSample(pt Point3, u, v float64) Ray {
+	lp := l.PointToLocal(pt)
+	w := Vector3(lp)
+	if w.Norm() < Epsilon {
+		return NewRay(pt, l.PointToGlobal(Origin))
+	}
+	w.Normalize()
+	// tangent frame around w
+	a := Vector3{1, 0, 0}
+	if math.Abs(w[X]) > .9 {
+		a = Vector3{0, 1, 0}
+	}
+	t1 := w.Cross(a)
+	t1.Normalize()
+	t2 := w.Cross(t1)
+	x, y := concentricDisk(u, v)
+	h := math.Sqrt(math.Max(0, 1-x*x-y*y))
+	p := t1.Mult(x).Add(t2.Mult(y)).Add(w.Mult(h))
+	return NewRay(pt, l.PointToGlobal(Point3(p)))
+}
+
+// Translate applies a translation to the light
+func (l *SphereLight) Translate(x, y, z float64) *SphereLight {
+	l.Transform.Translate(x, y, z)
+	return l
+}
+
+// Scale applies a scaling transform to the light
+func (l *SphereLight) Scale(x, y, z float64) *SphereLight {
+	l.Transform.Scale(x, y, z)
+	return l
+}
@@ -0,0 +1,80 @@
+package ray
+
+import "math"
+
+// AttenuationModel is how the light decreases with the distance d to
+// the light.
+type AttenuationModel int
+
+const (
+	// NoAttenuation keeps the light constant
+	NoAttenuation AttenuationModel = iota
+	// InverseSquareAttenuation is the physical one, Intensity / d²
+	InverseSquareAttenuation
+	// PolynomialAttenuation is 1 / (Constant + Linear*d + Quadratic*d²)
+	PolynomialAttenuation
+	// ExponentialAttenuation is exp(-Rate*d)
+	ExponentialAttenuation
+)
+
+// Attenuation is the attenuation of a light with distance. The fields
+// not used by the model are ignored. If Range is set, points further
+// than Range get no light from it, and no shadow ray is cast to it.
+type Attenuation struct {
+	Model     AttenuationModel
+	Intensity float64 // inverse square
+	Constant  float64 // polynomial
+	Linear    float64 // polynomial
+	Quadratic float64 // polynomial
+	Rate      float64 // exponential
+	Range     float64 // none if 0
+}
+
+// DefaultAttenuation is the attenuation of new lights. As it depends on
+// the scale of the scene, lights of very big or small scenes should
+// rather use another one.
+var DefaultAttenuation = Attenuation{Model: ExponentialAttenuation, Rate: .01}
+
+// Factor returns the factor applied to the light color at distance d
+func (a *Attenuation) Factor(d float64) float64 {
+	if !a.InRange(d) {
+		return 0
+	}
+	switch a.Model {
+	case InverseSquareAttenuation:
+		return a.Intensity / math.Max(d*d, Epsilon)
+	case PolynomialAttenuation:
+		q := a.Constant + a.Linear*d + a.Quadratic*d*d
+		if q < Epsilon {
+			return 1 / Epsilon
+		}
+		return 1 / q
+	case ExponentialAttenuation:
+		return math.Exp(-a.Rate * d)
+	}
+	return 1
+}
+
+// InRange returns true if the light reaches the distance d
+func (a *Attenuation) InRange(d float64) bool {
+	return a.Range <= 0 || d <= a.Range
+}
+
+// attenuated is implemented by the lights having an Attenuation
+type attenuated interface {
+	attenuation() *Attenuation
+}
+
+// lightAttenuation returns the attenuation of li: none for sun lights,
+// DefaultAttenuation for the lights without their own.
+func lightAttenuation(li Light) *Attenuation {
+	if li.Sun() {
+		return &noAttenuation
+	}
+	if a, ok := li.(attenuated); ok {
+		return a.attenuation()
+	}
+	return &DefaultAttenuation
+}
+
+var noAttenuation = Attenuation{}
@@ -39,7 +39,21 @@ func (bb *BoundingBox) AddObjects(obj ...Object) {
 	}
 }
 
+func (bb *BoundingBox) prepare() {
+	for _, c := range bb.childs {
+		prepare(c)
+	}
+}
+
 func (bb *BoundingBox) mergeBB(o Object) {
+	min, max := globalMinMax(o)
+	bb.min = minPoint(bb.min, min)
+	bb.max = maxPoint(bb.max, max)
+}
+
+// globalMinMax returns the min max points of the object's bounding box
+// in global coords (the coords of its parent).
+func globalMinMax(o Object) (Point3, Point3) {
 	lmin, lmax := o.MinMax()
 	var x [8]Point3
 	// take all the local obj_bb points
@@ -51,29 +65,25 @@ func (bb *BoundingBox) mergeBB(o Object) {
 	x[5] = Point3{lmax[X], lmin[Y], lmax[Z]}
 	x[6] = Point3{lmax[X], lmax[Y], lmax[Z]}
 	x[7] = Point3{lmin[X], lmax[Y], lmax[Z]}
-	// transform them to global and adjust bb minmax
-	for i := 0; i < 8; i++ {
-		x[i] = o.PointToGlobal(x[i])
-		if x[i][X] < bb.min[X] {
-			bb.min[X] = x[i][X]
-		}
-		if x[i][Y] < bb.min[Y] {
-			bb.min[Y] = x[i][Y]
-		}
-		if x[i][Z] < bb.min[Z] {
-			bb.min[Z] = x[i][Z]
-		}
-		if x[i][X] > bb.max[X] {
-			bb.max[X] = x[i][X]
-		}
-		if x[i][Y] > bb.max[Y] {
-			bb.max[Y] = x[i][Y]
-		}
-		if x[i][Z] > bb.max[Z] {
-			bb.max[Z] = x[i][Z]
-		}
-
+	// transform them to global and adjust minmax
+	min := o.PointToGlobal(x[0])
+	max := min
+	for i := 1; i < 8; i++ {
+		p := o.PointToGlobal(x[i])
+		min = minPoint(min, p)
+		max = maxPoint(max, p)
 	}
+	return min, max
+}
+
+// minPoint returns the point made of the min coords of a and b.
+func minPoint(a, b Point3) Point3 {
+	return Point3{math.Min(a[X], b[X]), math.Min(a[Y], b[Y]), math.Min(a[Z], b[Z])}
+}
+
+// maxPoint returns the point made of the max coords of a and b.
+func maxPoint(a, b Point3) Point3 {
+	return Point3{math.Max(a[X], b[X]), math.Max(a[Y], b[Y]), math.Max(a[Z], b[Z])}
 }
 
 // Intersect ...
@@ -100,6 +110,8 @@ func (bb *BoundingBox) Intersect(r Ray) *Hit {
 	}
 	closest.globRay = bb.RayToGlobal(closest.globRay)
 	closest.globNorm = bb.RayToGlobal(closest.globNorm)
+	closest.dpdu = bb.VectorToGlobal(closest.dpdu)
+	closest.dpdv = bb.VectorToGlobal(closest.dpdv)
 	return closest
 }
 
@@ -0,0 +1,62 @@
+package ray
+
+// bumpDelta is the UV offset used to differentiate bump maps
+const bumpDelta = 1e-3
+
+// perturb modifies the shading normal of the hit following the surface
+// bump map (a height map) or normal map. The geometric normal is kept
+// in h.geoNorm, for ray offsets.
+func (s *Surface) perturb(h *Hit) {
+	h.geoNorm = h.globNorm.dir
+	if s.Bump == nil && s.NormalMap == nil {
+		return
+	}
+	if h.dpdu.Norm() < Epsilon || h.dpdv.Norm() < Epsilon {
+		return
+	}
+	// outward normal, the one the tangents are built around
+	n := h.dpdu.Cross(h.dpdv)
+	n.Normalize()
+
+	var pn Vector3
+	if s.NormalMap != nil {
+		pn = s.normalMapped(h, n)
+	} else {
+		pn = s.bumped(h, n)
+	}
+	pn.Normalize()
+	// keep facing the ray like the geometric normal
+	if pn.Dot(h.geoNorm) < 0 {
+		pn.Reverse()
+	}
+	h.globNorm.dir = pn
+}
+
+// bumped returns the normal displaced by the height map along n
+// (Blinn's bump mapping), the height being the texture gray level.
+func (s *Surface) bumped(h *Hit, n Vector3) Vector3 {
+	h0 := s.Bump.ColorAt(h).gray()
+	hu := *h
+	hu.u += bumpDelta
+	hu.locNorm.pt = Point3(Vector3(h.locNorm.pt).Add(h.locDpdu.Mult(bumpDelta)))
+	hv := *h
+	hv.v += bumpDelta
+	hv.locNorm.pt = Point3(Vector3(h.locNorm.pt).Add(h.locDpdv.Mult(bumpDelta)))
+	dhdu := (s.Bump.ColorAt(&hu).gray() - h0) / bumpDelta * s.BumpScale
+	dhdv := (s.Bump.ColorAt(&hv).gray() - h0) / bumpDelta * s.BumpScale
+
+	pu := h.dpdu.Add(n.Mult(dhdu))
+	pv := h.dpdv.Add(n.Mult(dhdv))
+	return pu.Cross(pv)
+}
+
+// normalMapped returns the normal read from the tangent space normal map,
+// where the RGB components in [0,1] map to tangent, bitangent and normal in [-1,1].
+func (s *Surface) normalMapped(h *Hit, n Vector3) Vector3 {
+	c := s.NormalMap.ColorAt(h)
+	// orthonormal tangent frame
+	t := h.dpdu.Sub(n.Mult(n.Dot(h.dpdu)))
+	t.Normalize()
+	b := n.Cross(t)
+	return t.Mult(2*c.R - 1).Add(b.Mult(2*c.G - 1)).Add(n.Mult(2*c.B - 1))
+}
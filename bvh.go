@@ -0,0 +1,223 @@
+package ray
+
+import (
+	"math"
+	"sort"
+)
+
+// bvhLeafSize is the max number of objects in a BVH leaf
+const bvhLeafSize = 4
+
+// bvh is a bounding volume hierarchy over objects, in global coords.
+// It is built with the surface area heuristic (SAH).
+type bvh struct {
+	min    Point3
+	max    Point3
+	left   *bvh
+	right  *bvh
+	leaves []Object // only for leaf nodes
+}
+
+// bvhEntry is an object with its global bounding box
+type bvhEntry struct {
+	obj    Object
+	min    Point3
+	max    Point3
+	center Point3
+}
+
+// newBVH builds a BVH over the objects, using their global bounds.
+func newBVH(objects []Object) *bvh {
+	entries := make([]bvhEntry, len(objects))
+	for i, o := range objects {
+		min, max := globalMinMax(o)
+		// pad flat boxes (e.g. planes) so rays parallel to them don't miss
+		for a := X; a <= Z; a++ {
+			min[a] -= BigEpsilon
+			max[a] += BigEpsilon
+		}
+		entries[i] = bvhEntry{
+			obj: o, min: min, max: max,
+			center: Point3{(min[X] + max[X]) / 2, (min[Y] + max[Y]) / 2, (min[Z] + max[Z]) / 2},
+		}
+	}
+	return buildBVH(entries)
+}
+
+// area returns the surface area of the box, used by the SAH
+func area(min, max Point3) float64 {
+	dx, dy, dz := max[X]-min[X], max[Y]-min[Y], max[Z]-min[Z]
+	return 2 * (dx*dy + dy*dz + dz*dx)
+}
+
+func buildBVH(entries []bvhEntry) *bvh {
+	n := &bvh{}
+	if len(entries) == 0 {
+		return n
+	}
+	n.min, n.max = entries[0].min, entries[0].max
+	for _, e := range entries[1:] {
+		n.min = minPoint(n.min, e.min)
+		n.max = maxPoint(n.max, e.max)
+	}
+	if len(entries) <= bvhLeafSize {
+		n.setLeaves(entries)
+		return n
+	}
+
+	// find the axis and position of the cheapest split, by sweeping
+	// the objects sorted by their center on each axis
+	bestCost := math.Inf(1)
+	bestAxis, bestSplit := -1, 0
+	rightArea := make([]float64, len(entries))
+	for a := X; a <= Z; a++ {
+		sortEntries(entries, a)
+		min, max := entries[len(entries)-1].min, entries[len(entries)-1].max
+		for i := len(entries) - 1; i > 0; i-- {
+			min = minPoint(min, entries[i].min)
+			max = maxPoint(max, entries[i].max)
+			rightArea[i] = area(min, max)
+		}
+		min, max = entries[0].min, entries[0].max
+		for i := 1; i < len(entries); i++ {
+			// left is [0,i), right is [i,n)
+			cost := area(min, max)*float64(i) + rightArea[i]*float64(len(entries)-i)
+			if cost < bestCost {
+				bestCost, bestAxis, bestSplit = cost, a, i
+			}
+			min = minPoint(min, entries[i].min)
+			max = maxPoint(max, entries[i].max)
+		}
+	}
+	// not splitting is cheaper: make a leaf
+	if bestCost >= area(n.min, n.max)*float64(len(entries)) {
+		n.setLeaves(entries)
+		return n
+	}
+	if bestAxis != Z {
+		sortEntries(entries, bestAxis)
+	}
+	n.left = buildBVH(entries[:bestSplit])
+	n.right = buildBVH(entries[bestSplit:])
+	return n
+}
+
+func sortEntries(entries []bvhEntry, axis int) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].center[axis] < entries[j].center[axis]
+	})
+}
+
+func (n *bvh) setLeaves(entries []bvhEntry) {
+	n.leaves = make([]Object, len(entries))
+	for i, e := range entries {
+		n.leaves[i] = e.obj
+	}
+}
+
+// hitBox returns the ray parameter where the ray enters the node box,
+// and false if it misses it or enters it further than tmax.
+func (n *bvh) hitBox(r Ray, inv Vector3, tmax float64) (float64, bool) {
+	return hitBox(n.min, n.max, r, inv, tmax)
+}
+
+// hitBox returns the ray parameter where the ray enters the axis aligned
+// box min max, and false if it misses it or enters it further than tmax.
+// inv is the inverse of the ray direction.
+func hitBox(min, max Point3, r Ray, inv Vector3, tmax float64) (float64, bool) {
+	tmin := 0.0
+	for a := X; a <= Z; a++ {
+		if r.dir[a] == 0 {
+			if r.pt[a] < min[a] || r.pt[a] > max[a] {
+				return 0, false
+			}
+			continue
+		}
+		t1 := (min[a] - r.pt[a]) * inv[a]
+		t2 := (max[a] - r.pt[a]) * inv[a]
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tmin = math.Max(tmin, t1)
+		tmax = math.Min(tmax, t2)
+		if tmin > tmax {
+			return 0, false
+		}
+	}
+	return tmin, true
+}
+
+func inverseDir(r Ray) Vector3 {
+	return Vector3{1 / r.dir[X], 1 / r.dir[Y], 1 / r.dir[Z]}
+}
+
+// intersect finds the closest intersecting object, if any.
+func (n *bvh) intersect(r Ray) *Hit {
+	norm := r.dir.Norm()
+	if norm < Epsilon {
+		return nil
+	}
+	var h *Hit
+	n.closest(r, inverseDir(r), norm, math.MaxFloat64, &h)
+	return h
+}
+
+// closest updates h if a hit closer than t is found in the node,
+// and returns the ray parameter of the closest hit.
+func (n *bvh) closest(r Ray, inv Vector3, norm float64, t float64, h **Hit) float64 {
+	if _, ok := n.hitBox(r, inv, t); !ok {
+		return t
+	}
+	for _, o := range n.leaves {
+		s := o.Intersect(r)
+		if s == nil {
+			continue
+		}
+		st := r.pt.Dist(s.globNorm.pt) / norm
+		if st < t {
+			t = st
+			*h = s
+		}
+	}
+	if n.left == nil {
+		return t
+	}
+	// visit the nearest child first, so that the other may be pruned
+	first, second := n.left, n.right
+	tl, okl := n.left.hitBox(r, inv, t)
+	tr, okr := n.right.hitBox(r, inv, t)
+	if okr && (!okl || tr < tl) {
+		first, second = n.right, n.left
+	}
+	t = first.closest(r, inv, norm, t, h)
+	return second.closest(r, inv, norm, t, h)
+}
+
+// hidden returns true if the ray intersects an object closer than dist.
+func (n *bvh) hidden(rl Ray, dist float64) bool {
+	norm := rl.dir.Norm()
+	if norm < Epsilon {
+		return false
+	}
+	return n.anyHit(rl, inverseDir(rl), dist/norm, dist)
+}
+
+func (n *bvh) anyHit(rl Ray, inv Vector3, tmax float64, dist float64) bool {
+	if _, ok := n.hitBox(rl, inv, tmax); !ok {
+		return false
+	}
+	for _, obj := range n.leaves {
+		h := obj.Intersect(rl)
+		if h == nil {
+			continue
+		}
+		v := NewVec(rl.pt, h.globNorm.pt)
+		if v.Norm() < dist {
+			return true
+		}
+	}
+	if n.left == nil {
+		return false
+	}
+	return n.left.anyHit(rl, inv, tmax, dist) || n.right.anyHit(rl, inv, tmax, dist)
+}
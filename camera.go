@@ -26,13 +26,33 @@ func NewCamera(f, dx, dy float64, w int) *Camera {
 		Width: w, Height: h, Image: img}
 }
 
+// SetWidth changes the image width, the height follows the camera aspect ratio.
+// The previous image is discarded.
+func (c *Camera) SetWidth(w int) {
+	c.Width = w
+	c.Height = int(math.Round(float64(w) * c.dy / c.dx))
+	c.Image = image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))
+}
+
 // BuildRay creates a Ray in global space given an image pixel position
 func (c *Camera) BuildRay(x, y int) Ray {
-	X := (float64(x)*c.dx)/float64(c.Width) - c.dx/2
-	Y := c.dy/2 - float64(y)*c.dy/float64(c.Height)
+	return c.BuildRayAt(float64(x), float64(y))
+}
+
+// BuildRayAt creates a Ray in global space given an image position, in
+// pixels: the pixel x, y covers [x, x+1) by [y, y+1).
+func (c *Camera) BuildRayAt(x, y float64) Ray {
+	X := (x*c.dx)/float64(c.Width) - c.dx/2
+	Y := c.dy/2 - y*c.dy/float64(c.Height)
 	return c.RayToGlobal(Ray{pt: Origin, dir: Vector3{X, Y, -c.f}})
 }
 
+// pixelAngle returns the angle covered by a pixel, in radians
+// (approximated by its tangent).
+func (c *Camera) pixelAngle() float64 {
+	return c.dx / float64(c.Width) / c.f
+}
+
 // Project ...
 func (c *Camera) Project(p Point3) (int, int) {
 	lp := c.PointToLocal(p)
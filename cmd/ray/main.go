@@ -0,0 +1,146 @@
+// Command ray renders a JSON scene file (see ray.ReadScene) to a PNG or JPEG image.
+//
+// Usage:
+//
+//	ray [flags] scene.json
+//
+// Exit status is 0 on success, 1 if the scene can't be loaded, rendered
+// or written, and 2 on invalid command line.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image/png"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/dlecorfec/ray"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] scene.json\n", filepath.Base(os.Args[0]))
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("ray: ")
+
+	out := flag.String("o", "", "output `file`, .png or .jpg (default: scene name with .png, - for stdout)")
+	width := flag.Int("width", 0, "image width in pixels, height follows the camera aspect ratio (default: from scene)")
+	workers := flag.Int("workers", 0, "number of trace workers (default: number of CPUs / 4)")
+	depth := flag.Int("depth", 0, "max tracing recursion level (default: from scene)")
+	samples := flag.Int("samples", 0, "rays per pixel, for antialiasing (default: from scene)")
+	filter := flag.String("filter", "", "pixel `filter` of the samples: box, tent, gaussian or mitchell (default: from scene)")
+	adaptive := flag.Float64("adaptive", 0, "adaptive antialiasing color `threshold`, instead of -samples (default: from scene)")
+	adaptiveDepth := flag.Int("adaptive-depth", 0, "max subdivisions of a pixel by adaptive antialiasing (default: from scene)")
+	refined := flag.String("refined", "", "write the pixels refined by adaptive antialiasing to this PNG `file`")
+	preview := flag.Bool("preview", false, "show the rendering in a window")
+	noPreview := flag.Bool("no-preview", false, "render without any window (default)")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+	if *preview && *noPreview {
+		log.Print("-preview and -no-preview are exclusive")
+		os.Exit(2)
+	}
+	if *width < 0 || *workers < 0 || *depth < 0 || *samples < 0 || *adaptive < 0 || *adaptiveDepth < 0 {
+		log.Print("-width, -workers, -depth, -samples, -adaptive and -adaptive-depth must not be negative")
+		os.Exit(2)
+	}
+	pf, ok := ray.PixelFilters[*filter]
+	if *filter != "" && !ok {
+		log.Printf("unknown filter %q", *filter)
+		os.Exit(2)
+	}
+	name := flag.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(name, filepath.Ext(name)) + ".png"
+	}
+
+	s, err := ray.ReadSceneFile(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *width > 0 {
+		s.Camera().SetWidth(*width)
+	}
+	if *depth > 0 {
+		s.MaxDepth = *depth
+	}
+	if *samples > 0 {
+		s.Samples = *samples
+	}
+	if pf != nil {
+		s.PixelFilter = pf
+	}
+	if *adaptive > 0 {
+		s.AdaptiveThreshold = *adaptive
+	}
+	if *adaptiveDepth > 0 {
+		s.AdaptiveDepth = *adaptiveDepth
+	}
+	s.Workers = *workers
+	s.Preview = *preview
+
+	if s.Preview {
+		s.Raytrace()
+	} else {
+		ctx, cancel := context.WithCancel(context.Background())
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		go func() {
+			<-sig
+			cancel()
+		}()
+		_, err := s.Render(ctx)
+		cancel()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(*out)) {
+	case ".jpg", ".jpeg":
+		err = s.WriteJPG(*out)
+	default:
+		if *out == "-" {
+			*out = ""
+		}
+		err = s.WritePNG(*out)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *refined != "" {
+		if err := writeRefined(s, *refined); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// writeRefined writes the pixels refined by adaptive antialiasing
+func writeRefined(s *ray.Scene, name string) error {
+	img := s.RefinedImage()
+	if img == nil {
+		return fmt.Errorf("-refined: no adaptive antialiasing")
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,238 @@
+package ray
+
+import "math"
+
+// Cone is a canonical cone around the y-axis, of radius 1 at y = -1
+// and of radius Top at y = 1: 0 for a pointed cone, more for a
+// truncated one. It is closed by caps unless Open is set.
+type Cone struct {
+	Transform
+	Surface
+	name string
+	Top  float64 // in [0, 1), a cylinder being a Cylinder
+	Open bool
+}
+
+// NewCone creates a pointed cone, of apex (0, 1, 0)
+func NewCone() *Cone {
+	return &Cone{
+		Transform: IDTransform,
+		Surface:   DefaultSurface,
+	}
+}
+
+// SetName ...
+func (c *Cone) SetName(name string) {
+	c.name = "cone:" + name
+}
+
+// Name returns the cone's name
+func (c *Cone) Name() string {
+	return c.name
+}
+
+// Surf ...
+func (c *Cone) Surf() *Surface {
+	return &c.Surface
+}
+
+// Translate applies a translation to the cone
+func (c *Cone) Translate(x, y, z float64) *Cone {
+	c.Transform.Translate(x, y, z)
+	return c
+}
+
+// RotateX applies a rotation around x-axis to the cone
+func (c *Cone) RotateX(x float64) *Cone {
+	c.Transform.RotateX(x)
+	return c
+}
+
+// RotateY applies a rotation around y-axis to the cone
+func (c *Cone) RotateY(y float64) *Cone {
+	c.Transform.RotateY(y)
+	return c
+}
+
+// RotateZ applies a rotation around z-axis to the cone
+func (c *Cone) RotateZ(z float64) *Cone {
+	c.Transform.RotateZ(z)
+	return c
+}
+
+// Scale applies a scaling transform to the cone
+func (c *Cone) Scale(x, y, z float64) *Cone {
+	c.Transform.Scale(x, y, z)
+	return c
+}
+
+// Intersect ...
+func (c *Cone) Intersect(r Ray) *Hit {
+	locRay := c.RayToLocal(r)
+	t, part, ok := intersectCone(locRay, c.Top, c.Open)
+	if !ok {
+		return nil
+	}
+	return coneHit(&c.Transform, &c.Surface, r, locRay, t, part, c.Top)
+}
+
+// Spans implements Solid, an open cone being closed by its caps
+func (c *Cone) Spans(r Ray) []Span {
+	return coneSpans(&c.Transform, &c.Surface, r, c.Top)
+}
+
+// MinMax ...
+func (c *Cone) MinMax() (Point3, Point3) {
+	m := math.Max(1, c.Top)
+	return Point3{-m, -1, -m}, Point3{m, 1, m}
+}
+
+// parts of a cone or cylinder
+const (
+	coneSide = iota
+	coneBottom
+	coneTop
+)
+
+// coneCrossing is an intersection of a ray and a part of a cone
+type coneCrossing struct {
+	t    float64
+	part int
+}
+
+// coneCrossings returns the intersections of the local ray and the cone
+// of radius 1 at y = -1 and top at y = 1, whatever the sign of t.
+// A cylinder is a cone with top = 1.
+func coneCrossings(r Ray, top float64, open bool) ([4]coneCrossing, int) {
+	var xs [4]coneCrossing
+	n := 0
+	p, d := r.pt, r.dir
+
+	// side: x² + z² = (a + b*y)²
+	a, b := (1+top)/2, (top-1)/2
+	ra, rb := a+b*p[Y], b*d[Y] // radius along the ray: ra + t*rb
+	qa := square(d[X]) + square(d[Z]) - square(rb)
+	qb := 2 * (p[X]*d[X] + p[Z]*d[Z] - ra*rb)
+	qc := square(p[X]) + square(p[Z]) - square(ra)
+	side := func(t float64) {
+		if y := p[Y] + t*d[Y]; y >= -1 && y <= 1 {
+			xs[n] = coneCrossing{t, coneSide}
+			n++
+		}
+	}
+	if isNul(qa) {
+		if !isNul(qb) {
+			side(-qc / qb)
+		}
+	} else if delta := square(qb) - 4*qa*qc; delta >= 0 {
+		sqd := math.Sqrt(delta)
+		side((-qb - sqd) / (2 * qa))
+		side((-qb + sqd) / (2 * qa))
+	}
+
+	// caps
+	if !open && !isNul(d[Y]) {
+		t := (-1 - p[Y]) / d[Y]
+		if square(p[X]+t*d[X])+square(p[Z]+t*d[Z]) <= 1 {
+			xs[n] = coneCrossing{t, coneBottom}
+			n++
+		}
+		if top > 0 {
+			t = (1 - p[Y]) / d[Y]
+			if square(p[X]+t*d[X])+square(p[Z]+t*d[Z]) <= square(top) {
+				xs[n] = coneCrossing{t, coneTop}
+				n++
+			}
+		}
+	}
+	return xs, n
+}
+
+// intersectCone returns the nearest intersection of the local ray and
+// the cone, and the part which is hit.
+func intersectCone(r Ray, top float64, open bool) (float64, int, bool) {
+	xs, n := coneCrossings(r, top, open)
+	best, part := math.MaxFloat64, -1
+	for _, x := range xs[:n] {
+		if x.t > Epsilon && x.t < best {
+			best, part = x.t, x.part
+		}
+	}
+	return best, part, part >= 0
+}
+
+// coneSpans returns the span of the ray inside the closed cone, which is
+// convex: from its first to its last intersection.
+func coneSpans(tr *Transform, surf *Surface, r Ray, top float64) []Span {
+	locRay := tr.RayToLocal(r)
+	xs, n := coneCrossings(locRay, top, false)
+	if n < 2 {
+		return nil
+	}
+	first, last := xs[0], xs[0]
+	for _, x := range xs[1:n] {
+		if x.t < first.t {
+			first = x
+		}
+		if x.t > last.t {
+			last = x
+		}
+	}
+	return []Span{{
+		T0: first.t,
+		T1: last.t,
+		H0: coneHit(tr, surf, r, locRay, first.t, first.part, top),
+		H1: coneHit(tr, surf, r, locRay, last.t, last.part, top),
+	}}
+}
+
+// coneHit builds the hit at ray parameter t on the given part of a cone.
+func coneHit(tr *Transform, surf *Surface, r, locRay Ray, t float64, part int, top float64) *Hit {
+	var h Hit
+	h.globRay = r
+	h.locRay = locRay
+	lp := Point3{
+		locRay.pt[X] + t*locRay.dir[X],
+		locRay.pt[Y] + t*locRay.dir[Y],
+		locRay.pt[Z] + t*locRay.dir[Z],
+	}
+	h.locNorm.pt = lp
+	var dpdu, dpdv Vector3
+	switch part {
+	case coneSide:
+		b := (top - 1) / 2
+		rad := (1+top)/2 + b*lp[Y]
+		phi := math.Atan2(-lp[Z], lp[X])
+		if phi < 0 {
+			phi += 2 * math.Pi
+		}
+		cos, sin := math.Cos(phi), math.Sin(phi)
+		h.locNorm.dir = Vector3{cos, -b, -sin}
+		h.u, h.v = phi/(2*math.Pi), (lp[Y]+1)/2
+		if rad < Epsilon {
+			// apex: any tangent frame will do
+			rad = 1
+		}
+		dpdu = Vector3{-2 * math.Pi * rad * sin, 0, -2 * math.Pi * rad * cos}
+		dpdv = Vector3{2 * b * cos, 2, -2 * b * sin}
+	case coneBottom:
+		h.locNorm.dir = Vector3{0, -1, 0}
+		h.u, h.v = (lp[X]+1)/2, (lp[Z]+1)/2
+		dpdu, dpdv = Vector3{2, 0, 0}, Vector3{0, 0, 2}
+	default:
+		h.locNorm.dir = Vector3{0, 1, 0}
+		h.u, h.v = (lp[X]+1)/2, (1-lp[Z])/2
+		dpdu, dpdv = Vector3{2, 0, 0}, Vector3{0, 0, -2}
+	}
+	h.setTangents(tr, dpdu, dpdv)
+	// the normal must face the ray, which comes from inside the cone
+	if h.locNorm.dir.Dot(locRay.dir) > 0 {
+		h.locNorm.dir.Reverse()
+		h.inside = true
+	}
+	h.globNorm.pt = tr.PointToGlobal(lp)
+	h.globNorm.dir = tr.NormalToGlobal(h.locNorm.dir)
+	h.globNorm.Normalize()
+	h.Surface = surf
+	return &h
+}
@@ -0,0 +1,233 @@
+package ray
+
+import (
+	"fmt"
+	"math"
+)
+
+// Solid is an Object enclosing a volume, which can be combined by CSG.
+type Solid interface {
+	Object
+	// Spans returns the intervals of the ray inside the solid, in
+	// increasing order, including the ones behind the ray origin.
+	Spans(r Ray) []Span
+}
+
+// Span is an interval of a ray inside a solid, between the points at ray
+// parameters T0 and T1 (r.pt + t*r.dir), with the hits on the surface
+// of the solid at both ends.
+type Span struct {
+	T0, T1 float64
+	H0, H1 *Hit
+}
+
+// CSGOp is a boolean operation of constructive solid geometry
+type CSGOp int
+
+// CSG operations
+const (
+	Union        CSGOp = iota // points in A or B
+	Intersection              // points in A and B
+	Difference                // points in A but not in B
+)
+
+var csgOpNames = [...]string{"union", "intersection", "difference"}
+
+func (op CSGOp) String() string {
+	if op < 0 || int(op) >= len(csgOpNames) {
+		return fmt.Sprintf("CSGOp(%d)", int(op))
+	}
+	return csgOpNames[op]
+}
+
+func parseCSGOp(s string) (CSGOp, error) {
+	for i, n := range csgOpNames {
+		if n == s {
+			return CSGOp(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown CSG operation %q", s)
+}
+
+// inside returns whether a point is inside the result, given if it is
+// inside A and B
+func (op CSGOp) inside(a, b bool) bool {
+	switch op {
+	case Union:
+		return a || b
+	case Intersection:
+		return a && b
+	}
+	return a && !b
+}
+
+// CSG is the combination of 2 solids by a boolean operation. The
+// surfaces of the result are the ones of the solids.
+type CSG struct {
+	Transform
+	name string
+	Op   CSGOp
+	A, B Solid
+}
+
+// NewCSG creates the combination of solids a and b by operation op,
+// which are in the local coords of the CSG. Instances are solids only if
+// their object is one, otherwise an error is returned.
+func NewCSG(op CSGOp, a, b Solid) (*CSG, error) {
+	for _, s := range []Solid{a, b} {
+		if in, ok := s.(*Instance); ok && !in.isSolid() {
+			return nil, fmt.Errorf("csg: instance of %T is not a solid", in.Object)
+		}
+	}
+	return &CSG{
+		Transform: IDTransform,
+		Op:        op,
+		A:         a,
+		B:         b,
+	}, nil
+}
+
+// SetName ...
+func (c *CSG) SetName(name string) {
+	c.name = "csg:" + name
+}
+
+// Name returns the CSG's name
+func (c *CSG) Name() string {
+	return c.name
+}
+
+// Translate applies a translation to the CSG
+func (c *CSG) Translate(x, y, z float64) *CSG {
+	c.Transform.Translate(x, y, z)
+	return c
+}
+
+// RotateX applies a rotation around x-axis to the CSG
+func (c *CSG) RotateX(x float64) *CSG {
+	c.Transform.RotateX(x)
+	return c
+}
+
+// RotateY applies a rotation around y-axis to the CSG
+func (c *CSG) RotateY(y float64) *CSG {
+	c.Transform.RotateY(y)
+	return c
+}
+
+// RotateZ applies a rotation around z-axis to the CSG
+func (c *CSG) RotateZ(z float64) *CSG {
+	c.Transform.RotateZ(z)
+	return c
+}
+
+// Scale applies a scaling transform to the CSG
+func (c *CSG) Scale(x, y, z float64) *CSG {
+	c.Transform.Scale(x, y, z)
+	return c
+}
+
+func (c *CSG) prepare() {
+	prepare(c.A)
+	prepare(c.B)
+}
+
+// Intersect returns the first boundary of the spans in front of the ray
+func (c *CSG) Intersect(r Ray) *Hit {
+	locRay := c.RayToLocal(r)
+	min, max := c.MinMax()
+	if _, ok := hitBox(min, max, locRay, inverseDir(locRay), math.MaxFloat64); !ok {
+		return nil
+	}
+	for _, s := range c.spans(locRay) {
+		var h *Hit
+		switch {
+		case s.T0 > Epsilon:
+			h = s.H0
+		case s.T1 > Epsilon:
+			h = s.H1
+		default:
+			continue
+		}
+		h.toParent(&c.Transform)
+		return h
+	}
+	return nil
+}
+
+// Spans implements Solid
+func (c *CSG) Spans(r Ray) []Span {
+	spans := c.spans(c.RayToLocal(r))
+	for _, s := range spans {
+		s.H0.toParent(&c.Transform)
+		s.H1.toParent(&c.Transform)
+	}
+	return spans
+}
+
+// spans returns the spans of the local ray, merging the ones of A and B
+// in increasing order of t, and keeping their boundaries where the
+// inside of the result changes.
+func (c *CSG) spans(locRay Ray) []Span {
+	a, b := c.A.Spans(locRay), c.B.Spans(locRay)
+	var spans []Span
+	var cur Span
+	i, j := 0, 0 // indices of the span boundaries
+	inA, inB, in := false, false, false
+	for i < 2*len(a) || j < 2*len(b) {
+		ta, ha := spanEnd(a, i)
+		tb, hb := spanEnd(b, j)
+		var t float64
+		var h *Hit
+		if ta <= tb {
+			t, h = ta, ha
+			inA = i%2 == 0
+			i++
+		} else {
+			t, h = tb, hb
+			inB = j%2 == 0
+			j++
+		}
+		now := c.Op.inside(inA, inB)
+		if now == in {
+			continue
+		}
+		// the hit inside flag is now relative to the result
+		if now {
+			h.inside = false
+			cur = Span{T0: t, H0: h}
+		} else {
+			h.inside = true
+			cur.T1, cur.H1 = t, h
+			spans = append(spans, cur)
+		}
+		in = now
+	}
+	return spans
+}
+
+// spanEnd returns the i-th boundary of the spans, +Inf after the last one
+func spanEnd(spans []Span, i int) (float64, *Hit) {
+	if i >= 2*len(spans) {
+		return math.Inf(1), nil
+	}
+	s := spans[i/2]
+	if i%2 == 0 {
+		return s.T0, s.H0
+	}
+	return s.T1, s.H1
+}
+
+// MinMax returns the bounds of the result: the union or the
+// intersection of the bounds of A and B, or the bounds of A.
+func (c *CSG) MinMax() (Point3, Point3) {
+	amin, amax := globalMinMax(c.A)
+	bmin, bmax := globalMinMax(c.B)
+	switch c.Op {
+	case Union:
+		return minPoint(amin, bmin), maxPoint(amax, bmax)
+	case Intersection:
+		return maxPoint(amin, bmin), minPoint(amax, bmax)
+	}
+	return amin, amax
+}
@@ -69,9 +69,8 @@ func (c *Cube) Intersect(r Ray) *Hit {
 	localPoint := locRay.pt
 
 	minT := math.MaxFloat64
-	var h Hit
-	h.globRay = r
-	h.locRay = locRay
+	var lp Point3
+	var n Vector3
 
 	// inters y = -1 et y = 1
 	if !isNul(localDir[Y]) {
@@ -83,8 +82,8 @@ func (c *Cube) Intersect(r Ray) *Hit {
 				z = localPoint[Z] + t*localDir[Z]
 				if (z > -1-Epsilon) && (z < 1-Epsilon) {
 					minT = t
-					h.locNorm.pt = Point3{x, -1, z}
-					h.locNorm.dir = Vector3{0, -1, 0}
+					lp = Point3{x, -1, z}
+					n = Vector3{0, -1, 0}
 				}
 			}
 		}
@@ -96,8 +95,8 @@ func (c *Cube) Intersect(r Ray) *Hit {
 				z = localPoint[Z] + t*localDir[Z]
 				if (z > -1+Epsilon) && (z < 1+Epsilon) {
 					minT = t
-					h.locNorm.pt = Point3{x, 1, z}
-					h.locNorm.dir = Vector3{0, 1, 0}
+					lp = Point3{x, 1, z}
+					n = Vector3{0, 1, 0}
 				}
 			}
 		}
@@ -112,8 +111,8 @@ func (c *Cube) Intersect(r Ray) *Hit {
 				z = localPoint[Z] + t*localDir[Z]
 				if (z > -1+Epsilon) && (z < 1+Epsilon) {
 					minT = t
-					h.locNorm.pt = Point3{-1, y, z}
-					h.locNorm.dir = Vector3{-1, 0, 0}
+					lp = Point3{-1, y, z}
+					n = Vector3{-1, 0, 0}
 				}
 			}
 		}
@@ -125,8 +124,8 @@ func (c *Cube) Intersect(r Ray) *Hit {
 				z = localPoint[Z] + t*localDir[Z]
 				if (z > -1-Epsilon) && (z < 1-Epsilon) {
 					minT = t
-					h.locNorm.pt = Point3{1, y, z}
-					h.locNorm.dir = Vector3{1, 0, 0}
+					lp = Point3{1, y, z}
+					n = Vector3{1, 0, 0}
 				}
 			}
 		}
@@ -142,8 +141,8 @@ func (c *Cube) Intersect(r Ray) *Hit {
 				x = localPoint[X] + t*localDir[X]
 				if (x > -1-Epsilon) && (x < 1-Epsilon) {
 					minT = t
-					h.locNorm.pt = Point3{x, y, -1}
-					h.locNorm.dir = Vector3{0, 0, -1}
+					lp = Point3{x, y, -1}
+					n = Vector3{0, 0, -1}
 				}
 			}
 		}
@@ -155,22 +154,102 @@ func (c *Cube) Intersect(r Ray) *Hit {
 				x = localPoint[X] + t*localDir[X]
 				if (x > -1+Epsilon) && (x < 1+Epsilon) {
 					minT = t
-					h.locNorm.pt = Point3{x, y, 1}
-					h.locNorm.dir = Vector3{0, 0, 1}
+					lp = Point3{x, y, 1}
+					n = Vector3{0, 0, 1}
 				}
 			}
 		}
 	}
 
 	if minT < math.MaxFloat64-Epsilon {
-		h.globNorm = c.RayToGlobal(h.locNorm)
-		h.globNorm.Normalize()
-		h.Surface = &c.Surface
-		return &h
+		return c.hit(r, locRay, lp, n)
 	}
 	return nil
 }
 
+// Spans implements Solid
+func (c *Cube) Spans(r Ray) []Span {
+	locRay := c.RayToLocal(r)
+	p, d := locRay.pt, locRay.dir
+	t0, t1 := math.Inf(-1), math.Inf(1)
+	var n0, n1 Vector3
+	// slabs
+	for a := X; a <= Z; a++ {
+		if isNul(d[a]) {
+			if p[a] < -1 || p[a] > 1 {
+				return nil
+			}
+			continue
+		}
+		ta, tb := (-1-p[a])/d[a], (1-p[a])/d[a]
+		var na, nb Vector3
+		na[a], nb[a] = -1, 1
+		if ta > tb {
+			ta, tb, na, nb = tb, ta, nb, na
+		}
+		if ta > t0 {
+			t0, n0 = ta, na
+		}
+		if tb < t1 {
+			t1, n1 = tb, nb
+		}
+	}
+	if t0 >= t1 {
+		return nil
+	}
+	at := func(t float64) Point3 {
+		return Point3{p[X] + t*d[X], p[Y] + t*d[Y], p[Z] + t*d[Z]}
+	}
+	return []Span{{T0: t0, T1: t1, H0: c.hit(r, locRay, at(t0), n0), H1: c.hit(r, locRay, at(t1), n1)}}
+}
+
+// hit returns the hit at local point lp of the face of normal n
+func (c *Cube) hit(r, locRay Ray, lp Point3, n Vector3) *Hit {
+	var h Hit
+	h.globRay = r
+	h.locRay = locRay
+	h.locNorm.pt = lp
+	h.locNorm.dir = n
+	c.uv(&h)
+	// the normal must face the ray, which comes from inside the cube
+	if h.locNorm.dir.Dot(locRay.dir) > 0 {
+		h.locNorm.dir.Reverse()
+		h.inside = true
+	}
+	h.globNorm = c.RayToGlobal(h.locNorm)
+	h.globNorm.Normalize()
+	h.Surface = &c.Surface
+	return &h
+}
+
+// uv sets the per-face mapping of the hit: each face is mapped to [0,1]x[0,1],
+// such that dP/du x dP/dv is the outward normal.
+func (c *Cube) uv(h *Hit) {
+	p := h.locNorm.pt
+	var dpdu, dpdv Vector3
+	switch h.locNorm.dir {
+	case Vector3{1, 0, 0}:
+		h.u, h.v = (1-p[Z])/2, (p[Y]+1)/2
+		dpdu, dpdv = Vector3{0, 0, -2}, Vector3{0, 2, 0}
+	case Vector3{-1, 0, 0}:
+		h.u, h.v = (p[Z]+1)/2, (p[Y]+1)/2
+		dpdu, dpdv = Vector3{0, 0, 2}, Vector3{0, 2, 0}
+	case Vector3{0, 1, 0}:
+		h.u, h.v = (p[X]+1)/2, (1-p[Z])/2
+		dpdu, dpdv = Vector3{2, 0, 0}, Vector3{0, 0, -2}
+	case Vector3{0, -1, 0}:
+		h.u, h.v = (p[X]+1)/2, (p[Z]+1)/2
+		dpdu, dpdv = Vector3{2, 0, 0}, Vector3{0, 0, 2}
+	case Vector3{0, 0, 1}:
+		h.u, h.v = (p[X]+1)/2, (p[Y]+1)/2
+		dpdu, dpdv = Vector3{2, 0, 0}, Vector3{0, 2, 0}
+	default:
+		h.u, h.v = (1-p[X])/2, (p[Y]+1)/2
+		dpdu, dpdv = Vector3{-2, 0, 0}, Vector3{0, 2, 0}
+	}
+	h.setTangents(&c.Transform, dpdu, dpdv)
+}
+
 func (c *Cube) MinMax() (Point3, Point3) {
 	return Point3{-1, -1, -1}, Point3{1, 1, 1}
 }
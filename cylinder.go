@@ -0,0 +1,83 @@
+package ray
+
+// Cylinder is a canonical cylinder around the y-axis, of radius 1,
+// from y = -1 to y = 1. It is closed by caps unless Open is set.
+type Cylinder struct {
+	Transform
+	Surface
+	name string
+	Open bool
+}
+
+// NewCylinder creates a capped cylinder
+func NewCylinder() *Cylinder {
+	return &Cylinder{
+		Transform: IDTransform,
+		Surface:   DefaultSurface,
+	}
+}
+
+// SetName ...
+func (c *Cylinder) SetName(name string) {
+	c.name = "cylinder:" + name
+}
+
+// Name returns the cylinder's name
+func (c *Cylinder) Name() string {
+	return c.name
+}
+
+// Surf ...
+func (c *Cylinder) Surf() *Surface {
+	return &c.Surface
+}
+
+// Translate applies a translation to the cylinder
+func (c *Cylinder) Translate(x, y, z float64) *Cylinder {
+	c.Transform.Translate(x, y, z)
+	return c
+}
+
+// RotateX applies a rotation around x-axis to the cylinder
+func (c *Cylinder) RotateX(x float64) *Cylinder {
+	c.Transform.RotateX(x)
+	return c
+}
+
+// RotateY applies a rotation around y-axis to the cylinder
+func (c *Cylinder) RotateY(y float64) *Cylinder {
+	c.Transform.RotateY(y)
+	return c
+}
+
+// RotateZ applies a rotation around z-axis to the cylinder
+func (c *Cylinder) RotateZ(z float64) *Cylinder {
+	c.Transform.RotateZ(z)
+	return c
+}
+
+// Scale applies a scaling transform to the cylinder
+func (c *Cylinder) Scale(x, y, z float64) *Cylinder {
+	c.Transform.Scale(x, y, z)
+	return c
+}
+
+// Intersect ...
+func (c *Cylinder) Intersect(r Ray) *Hit {
+	locRay := c.RayToLocal(r)
+	t, part, ok := intersectCone(locRay, 1, c.Open)
+	if !ok {
+		return nil
+	}
+	return coneHit(&c.Transform, &c.Surface, r, locRay, t, part, 1)
+}
+
+// Spans implements Solid, an open cylinder being closed by its caps
+func (c *Cylinder) Spans(r Ray) []Span {
+	return coneSpans(&c.Transform, &c.Surface, r, 1)
+}
+
+// MinMax ...
+func (c *Cylinder) MinMax() (Point3, Point3) {
+	return Point3{-1, -1, -1}, Point3{1, 1, 1}
+}
@@ -0,0 +1,101 @@
+package ray
+
+// Disk is a canonical disk of radius 1 centered on origin, in the xz plane.
+// Like Plane, it has no inside.
+type Disk struct {
+	Transform
+	Surface
+	name string
+}
+
+// NewDisk creates a disk
+func NewDisk() *Disk {
+	return &Disk{
+		Transform: IDTransform,
+		Surface:   DefaultSurface,
+	}
+}
+
+// SetName ...
+func (d *Disk) SetName(name string) {
+	d.name = "disk:" + name
+}
+
+// Name returns the disk's name
+func (d *Disk) Name() string {
+	return d.name
+}
+
+// Surf ...
+func (d *Disk) Surf() *Surface {
+	return &d.Surface
+}
+
+// Translate applies a translation to the disk
+func (d *Disk) Translate(x, y, z float64) *Disk {
+	d.Transform.Translate(x, y, z)
+	return d
+}
+
+// RotateX applies a rotation around x-axis to the disk
+func (d *Disk) RotateX(x float64) *Disk {
+	d.Transform.RotateX(x)
+	return d
+}
+
+// RotateY applies a rotation around y-axis to the disk
+func (d *Disk) RotateY(y float64) *Disk {
+	d.Transform.RotateY(y)
+	return d
+}
+
+// RotateZ applies a rotation around z-axis to the disk
+func (d *Disk) RotateZ(z float64) *Disk {
+	d.Transform.RotateZ(z)
+	return d
+}
+
+// Scale applies a scaling transform to the disk
+func (d *Disk) Scale(x, y, z float64) *Disk {
+	d.Transform.Scale(x, y, z)
+	return d
+}
+
+// Intersect ...
+func (d *Disk) Intersect(r Ray) *Hit {
+	locRay := d.RayToLocal(r)
+	if isNul(locRay.dir[Y]) {
+		return nil
+	}
+	t := -locRay.pt[Y] / locRay.dir[Y]
+	if t < Epsilon {
+		return nil
+	}
+	x := locRay.pt[X] + t*locRay.dir[X]
+	z := locRay.pt[Z] + t*locRay.dir[Z]
+	if square(x)+square(z) > 1 {
+		return nil
+	}
+	var h Hit
+	h.globRay = r
+	h.locRay = locRay
+	h.locNorm.pt = Point3{x, 0, z}
+	h.locNorm.dir[Y] = 1
+	if locRay.pt[Y] < 0 {
+		h.locNorm.dir[Y] = -1
+	}
+	h.globNorm.pt = d.PointToGlobal(h.locNorm.pt)
+	h.globNorm.dir = d.NormalToGlobal(h.locNorm.dir)
+	h.globNorm.Normalize()
+	// same mapping as the plane
+	h.u = (x + 1) / 2
+	h.v = (1 - z) / 2
+	h.setTangents(&d.Transform, Vector3{2, 0, 0}, Vector3{0, 0, -2})
+	h.Surface = &d.Surface
+	return &h
+}
+
+// MinMax ...
+func (d *Disk) MinMax() (Point3, Point3) {
+	return Point3{-1, 0, -1}, Point3{1, 0, 1}
+}
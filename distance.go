@@ -0,0 +1,199 @@
+package ray
+
+import "math"
+
+// DistanceFunc is a signed distance function: it returns the distance
+// from p to a surface, negative inside. It may underestimate the
+// distance, but must not overestimate it.
+type DistanceFunc func(p Point3) float64
+
+// SphereDist is the distance to a sphere of radius r centered on origin.
+func SphereDist(r float64) DistanceFunc {
+	return func(p Point3) float64 {
+		return Vector3(p).Norm() - r
+	}
+}
+
+// BoxDist is the distance to a box centered on origin, of half sizes x, y, z.
+func BoxDist(x, y, z float64) DistanceFunc {
+	b := Vector3{x, y, z}
+	return func(p Point3) float64 {
+		var q, out Vector3
+		for a := X; a <= Z; a++ {
+			q[a] = math.Abs(p[a]) - b[a]
+			out[a] = math.Max(q[a], 0)
+		}
+		return out.Norm() + math.Min(math.Max(q[X], math.Max(q[Y], q[Z])), 0)
+	}
+}
+
+// TorusDist is the distance to a torus around the y-axis, of given
+// major and minor radii.
+func TorusDist(major, minor float64) DistanceFunc {
+	return func(p Point3) float64 {
+		return math.Hypot(math.Hypot(p[X], p[Z])-major, p[Y]) - minor
+	}
+}
+
+// CylinderDist is the distance to a capped cylinder around the y-axis,
+// of radius r, from y = -h to y = h.
+func CylinderDist(r, h float64) DistanceFunc {
+	return func(p Point3) float64 {
+		dx := math.Hypot(p[X], p[Z]) - r
+		dy := math.Abs(p[Y]) - h
+		return math.Min(math.Max(dx, dy), 0) + math.Hypot(math.Max(dx, 0), math.Max(dy, 0))
+	}
+}
+
+// Mandelbulb is the distance estimator of the Mandelbulb fractal of given
+// power (8 for the classic one), computed with the given number of
+// iterations. It fits in a sphere of radius 1.2.
+func Mandelbulb(power float64, iterations int) DistanceFunc {
+	return func(p Point3) float64 {
+		z := p
+		dr, r := 1.0, 0.0
+		for i := 0; i < iterations; i++ {
+			r = Vector3(z).Norm()
+			if r > 2 || r == 0 {
+				break
+			}
+			// z = z^power + p, in spherical coords
+			theta := math.Acos(z[Z]/r) * power
+			phi := math.Atan2(z[Y], z[X]) * power
+			dr = math.Pow(r, power-1)*power*dr + 1
+			zr := math.Pow(r, power)
+			z = Point3{
+				zr*math.Sin(theta)*math.Cos(phi) + p[X],
+				zr*math.Sin(theta)*math.Sin(phi) + p[Y],
+				zr*math.Cos(theta) + p[Z],
+			}
+		}
+		if r == 0 {
+			return 0
+		}
+		return 0.5 * math.Log(r) * r / dr
+	}
+}
+
+// Union returns the distance to the union of the surfaces of f and g.
+func (f DistanceFunc) Union(g DistanceFunc) DistanceFunc {
+	return func(p Point3) float64 {
+		return math.Min(f(p), g(p))
+	}
+}
+
+// Intersection returns the distance to the intersection of f and g.
+func (f DistanceFunc) Intersection(g DistanceFunc) DistanceFunc {
+	return func(p Point3) float64 {
+		return math.Max(f(p), g(p))
+	}
+}
+
+// Difference returns the distance to f minus g.
+func (f DistanceFunc) Difference(g DistanceFunc) DistanceFunc {
+	return func(p Point3) float64 {
+		return math.Max(f(p), -g(p))
+	}
+}
+
+// SmoothUnion returns the union of f and g blended over distance k
+// (polynomial smooth min).
+func (f DistanceFunc) SmoothUnion(g DistanceFunc, k float64) DistanceFunc {
+	return func(p Point3) float64 {
+		return smoothMin(f(p), g(p), k)
+	}
+}
+
+// SmoothIntersection returns the intersection of f and g blended over
+// distance k.
+func (f DistanceFunc) SmoothIntersection(g DistanceFunc, k float64) DistanceFunc {
+	return func(p Point3) float64 {
+		return -smoothMin(-f(p), -g(p), k)
+	}
+}
+
+// SmoothDifference returns f minus g blended over distance k.
+func (f DistanceFunc) SmoothDifference(g DistanceFunc, k float64) DistanceFunc {
+	return func(p Point3) float64 {
+		return -smoothMin(-f(p), g(p), k)
+	}
+}
+
+// smoothMin returns the min of a and b, smoothed where they are closer than k
+func smoothMin(a, b, k float64) float64 {
+	if k <= 0 {
+		return math.Min(a, b)
+	}
+	h := math.Max(k-math.Abs(a-b), 0) / k
+	return math.Min(a, b) - h*h*k/4
+}
+
+// Translate returns f translated by x, y, z.
+func (f DistanceFunc) Translate(x, y, z float64) DistanceFunc {
+	return func(p Point3) float64 {
+		return f(Point3{p[X] - x, p[Y] - y, p[Z] - z})
+	}
+}
+
+// Scale returns f scaled by s, which is uniform to keep it a distance.
+func (f DistanceFunc) Scale(s float64) DistanceFunc {
+	return func(p Point3) float64 {
+		return f(scalePoint(p, 1/s)) * s
+	}
+}
+
+// RotateX returns f rotated around the x-axis.
+func (f DistanceFunc) RotateX(a float64) DistanceFunc {
+	return f.transform(RotationX(-a))
+}
+
+// RotateY returns f rotated around the y-axis.
+func (f DistanceFunc) RotateY(a float64) DistanceFunc {
+	return f.transform(RotationY(-a))
+}
+
+// RotateZ returns f rotated around the z-axis.
+func (f DistanceFunc) RotateZ(a float64) DistanceFunc {
+	return f.transform(RotationZ(-a))
+}
+
+// transform returns f applied to the points transformed by the
+// inverse transformation m, which must preserve distances.
+func (f DistanceFunc) transform(m Matrix4) DistanceFunc {
+	return func(p Point3) float64 {
+		return f(m.MulP(p))
+	}
+}
+
+// Twist returns f twisted around the y-axis, by k radians per unit.
+// The result is not an exact distance any more, so the SDF StepFactor
+// should be lowered, the more the higher k is.
+func (f DistanceFunc) Twist(k float64) DistanceFunc {
+	return func(p Point3) float64 {
+		s, c := math.Sincos(k * p[Y])
+		return f(Point3{c*p[X] - s*p[Z], p[Y], s*p[X] + c*p[Z]})
+	}
+}
+
+// Repeat returns f repeated infinitely with given period along each
+// axis, 0 for no repetition. f should fit in a period cell centered on
+// origin.
+func (f DistanceFunc) Repeat(x, y, z float64) DistanceFunc {
+	period := Vector3{x, y, z}
+	return func(p Point3) float64 {
+		for a := X; a <= Z; a++ {
+			if c := period[a]; c > 0 {
+				p[a] -= c * math.Floor(p[a]/c+0.5)
+			}
+		}
+		return f(p)
+	}
+}
+
+// Round returns f with its edges rounded by radius r, the surface
+// being pushed out by r.
+func (f DistanceFunc) Round(r float64) DistanceFunc {
+	return func(p Point3) float64 {
+		return f(p) - r
+	}
+}
@@ -0,0 +1,76 @@
+package ray
+
+import (
+	"image"
+	"log"
+	"math"
+)
+
+// sample is the color traced through a point of the image, in pixels
+type sample struct {
+	x, y float64
+	c    FloatColor
+}
+
+// film accumulates the samples of the image, each one added to the
+// pixels around it, weighted by the filter. It is only used by the draw
+// worker, so it needs no lock.
+type film struct {
+	w, h   int
+	f      PixelFilter
+	r      int          // filter radius, in whole pixels
+	sum    []FloatColor // weighted sum of the samples, per pixel
+	weight []float64    // sum of the weights, per pixel
+}
+
+// newFilm returns the film of a w x h image, using the filter f, or
+// DefaultPixelFilter if f has no positive radius.
+func newFilm(w, h int, f PixelFilter) *film {
+	if r := f.Radius(); !(r > 0) || math.IsInf(r, 1) {
+		log.Printf("pixel filter %T: bad radius %v, using the default filter", f, r)
+		f = DefaultPixelFilter
+	}
+	return &film{
+		w:      w,
+		h:      h,
+		f:      f,
+		r:      int(math.Ceil(f.Radius())),
+		sum:    make([]FloatColor, w*h),
+		weight: make([]float64, w*h),
+	}
+}
+
+// add weights the sample into the pixels whose center is within the
+// filter radius.
+func (fm *film) add(sm sample) {
+	rad := fm.f.Radius()
+	x0 := int(math.Max(0, math.Ceil(sm.x-.5-rad)))
+	x1 := int(math.Min(float64(fm.w-1), math.Floor(sm.x-.5+rad)))
+	y0 := int(math.Max(0, math.Ceil(sm.y-.5-rad)))
+	y1 := int(math.Min(float64(fm.h-1), math.Floor(sm.y-.5+rad)))
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			w := fm.f.Weight(sm.x-float64(x)-.5, sm.y-float64(y)-.5)
+			if w == 0 {
+				continue
+			}
+			i := y*fm.w + x
+			fm.sum[i].Add(sm.c.MulF(w))
+			fm.weight[i] += w
+		}
+	}
+}
+
+// develop writes to img the pixels which the samples of pixel x, y
+// contributed to. Pixels without positive weight yet are left as is.
+func (fm *film) develop(img *image.RGBA, x, y int) {
+	for py := clampIndex(y-fm.r, fm.h); py <= clampIndex(y+fm.r, fm.h); py++ {
+		for px := clampIndex(x-fm.r, fm.w); px <= clampIndex(x+fm.r, fm.w); px++ {
+			i := py*fm.w + px
+			if fm.weight[i] <= 0 {
+				continue
+			}
+			img.SetRGBA(px, py, fm.sum[i].MulF(1/fm.weight[i]).Color())
+		}
+	}
+}
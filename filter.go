@@ -0,0 +1,111 @@
+package ray
+
+import "math"
+
+// PixelFilter is a reconstruction filter: the color of a pixel is the
+// average of the samples traced around its center, weighted by the
+// filter. Filters wider than a pixel blend in the neighbouring pixels,
+// making smoother images.
+type PixelFilter interface {
+	// Radius is the half width of the filter, in pixels, > 0
+	Radius() float64
+	// Weight returns the weight of a sample at x, y pixels from the
+	// center, x and y in [-Radius, Radius]. It may be negative.
+	Weight(x, y float64) float64
+}
+
+// PixelFilters are the filters which can be referenced by name, e.g. in
+// a scene file.
+var PixelFilters = map[string]PixelFilter{
+	"box":      BoxFilter{R: .5},
+	"tent":     TentFilter{R: 1},
+	"gaussian": GaussianFilter{R: 1.5, Alpha: 2},
+	"mitchell": MitchellFilter{R: 2, B: 1. / 3, C: 1. / 3},
+}
+
+// DefaultPixelFilter is used by scenes without filter. It averages the
+// samples of the pixel square.
+var DefaultPixelFilter PixelFilter = BoxFilter{R: .5}
+
+// BoxFilter weights all samples equally
+type BoxFilter struct {
+	R float64
+}
+
+// Radius ...
+func (f BoxFilter) Radius() float64 {
+	return f.R
+}
+
+// Weight ...
+func (f BoxFilter) Weight(x, y float64) float64 {
+	return 1
+}
+
+// TentFilter weights samples linearly decreasing to 0 at the radius
+type TentFilter struct {
+	R float64
+}
+
+// Radius ...
+func (f TentFilter) Radius() float64 {
+	return f.R
+}
+
+// Weight ...
+func (f TentFilter) Weight(x, y float64) float64 {
+	return math.Max(0, 1-math.Abs(x)/f.R) * math.Max(0, 1-math.Abs(y)/f.R)
+}
+
+// GaussianFilter weights samples by a gaussian of given falloff Alpha,
+// shifted to 0 at the radius.
+type GaussianFilter struct {
+	R     float64
+	Alpha float64
+}
+
+// Radius ...
+func (f GaussianFilter) Radius() float64 {
+	return f.R
+}
+
+// Weight ...
+func (f GaussianFilter) Weight(x, y float64) float64 {
+	return f.gaussian(x) * f.gaussian(y)
+}
+
+func (f GaussianFilter) gaussian(d float64) float64 {
+	return math.Max(0, math.Exp(-f.Alpha*d*d)-math.Exp(-f.Alpha*f.R*f.R))
+}
+
+// MitchellFilter is the Mitchell-Netravali cubic filter, of parameters
+// B and C, sharper than the gaussian. B = C = 1/3 are the recommended
+// ones.
+type MitchellFilter struct {
+	R float64
+	B float64
+	C float64
+}
+
+// Radius ...
+func (f MitchellFilter) Radius() float64 {
+	return f.R
+}
+
+// Weight ...
+func (f MitchellFilter) Weight(x, y float64) float64 {
+	return f.mitchell(2*x/f.R) * f.mitchell(2*y/f.R)
+}
+
+// mitchell is the 1D filter over [-2, 2]
+func (f MitchellFilter) mitchell(x float64) float64 {
+	b, c := f.B, f.C
+	x = math.Abs(x)
+	switch {
+	case x < 1:
+		return ((12-9*b-6*c)*x*x*x + (-18+12*b+6*c)*x*x + (6 - 2*b)) / 6
+	case x < 2:
+		return ((-b-6*c)*x*x*x + (6*b+30*c)*x*x + (-12*b-48*c)*x + (8*b + 24*c)) / 6
+	}
+	return 0
+}
@@ -0,0 +1,246 @@
+package ray
+
+import "fmt"
+
+// Group is a node of the scene graph. Its children, which may be groups,
+// are in its local coords: the group transform is applied after theirs.
+// The BVH over its children is built when the scene is rendered, again
+// if the children or their transforms were changed since, so a group
+// must not be changed during a rendering. Groups can be found, moved and
+// removed by name.
+type Group struct {
+	Transform
+	name     string
+	parent   *Group
+	children []Object
+	bvh      *bvh        // over the children, nil if not built
+	bounds   [][2]Point3 // bounds of the children when the BVH was built
+}
+
+// NewGroup creates an empty group
+func NewGroup() *Group {
+	return &Group{
+		Transform: IDTransform,
+	}
+}
+
+// SetName ...
+func (g *Group) SetName(name string) {
+	g.name = "group:" + name
+}
+
+// Name returns the group's name
+func (g *Group) Name() string {
+	return g.name
+}
+
+// Translate applies a translation to the group
+func (g *Group) Translate(x, y, z float64) *Group {
+	g.Transform.Translate(x, y, z)
+	return g
+}
+
+// RotateX applies a rotation around x-axis to the group
+func (g *Group) RotateX(x float64) *Group {
+	g.Transform.RotateX(x)
+	return g
+}
+
+// RotateY applies a rotation around y-axis to the group
+func (g *Group) RotateY(y float64) *Group {
+	g.Transform.RotateY(y)
+	return g
+}
+
+// RotateZ applies a rotation around z-axis to the group
+func (g *Group) RotateZ(z float64) *Group {
+	g.Transform.RotateZ(z)
+	return g
+}
+
+// Scale applies a scaling transform to the group
+func (g *Group) Scale(x, y, z float64) *Group {
+	g.Transform.Scale(x, y, z)
+	return g
+}
+
+// Parent returns the group holding g, nil if none
+func (g *Group) Parent() *Group {
+	return g.parent
+}
+
+// Children returns the objects of the group
+func (g *Group) Children() []Object {
+	return g.children
+}
+
+// AddObjects adds objects to the group. A group is moved from its
+// previous parent, and can't be added to itself or to its descendants.
+func (g *Group) AddObjects(obj ...Object) error {
+	g.bvh = nil
+	for _, o := range obj {
+		if c, ok := o.(*Group); ok {
+			if c.isAncestorOf(g) {
+				return fmt.Errorf("group %q: can't hold its ancestor %q", g.name, c.name)
+			}
+			if c.parent != nil {
+				c.parent.remove(c)
+			}
+			c.parent = g
+		}
+		g.children = append(g.children, o)
+	}
+	return nil
+}
+
+// isAncestorOf returns true if g is c or holds c in its descendants
+func (g *Group) isAncestorOf(c *Group) bool {
+	for ; c != nil; c = c.parent {
+		if c == g {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the object of given name among the descendants of the
+// group, nil if none. The name is the one given to SetName, with or
+// without its type prefix (e.g. "sphere:ball" or "ball").
+func (g *Group) Find(name string) Object {
+	o, _ := g.find(name)
+	return o
+}
+
+// find returns the named descendant and its parent
+func (g *Group) find(name string) (Object, *Group) {
+	for _, c := range g.children {
+		if c.Name() == name || objectName(c) == name {
+			return c, g
+		}
+		if cg, ok := c.(*Group); ok {
+			if o, p := cg.find(name); o != nil {
+				return o, p
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Remove removes the named object from the descendants of the group,
+// and returns it, or nil if not found.
+func (g *Group) Remove(name string) Object {
+	o, p := g.find(name)
+	if o == nil {
+		return nil
+	}
+	p.remove(o)
+	if c, ok := o.(*Group); ok {
+		c.parent = nil
+	}
+	return o
+}
+
+// remove removes the child o
+func (g *Group) remove(o Object) {
+	for i, c := range g.children {
+		if c == o {
+			g.children = append(g.children[:i], g.children[i+1:]...)
+			g.bvh = nil
+			return
+		}
+	}
+}
+
+// Reparent moves the named descendant of the group to the group to,
+// keeping its own transform, which is now relative to to.
+func (g *Group) Reparent(name string, to *Group) error {
+	if to == nil {
+		return fmt.Errorf("group %q: no group to move %q to", g.name, name)
+	}
+	o, p := g.find(name)
+	if o == nil {
+		return fmt.Errorf("group %q: %q not found", g.name, name)
+	}
+	if c, ok := o.(*Group); ok {
+		// AddObjects removes it from its parent
+		return to.AddObjects(c)
+	}
+	p.remove(o)
+	return to.AddObjects(o)
+}
+
+// preparer is implemented by the objects to set up before rendering,
+// holding groups, as the trace workers only read the objects.
+type preparer interface {
+	prepare()
+}
+
+// prepare sets up o, if needed
+func prepare(o Object) {
+	if p, ok := o.(preparer); ok {
+		p.prepare()
+	}
+}
+
+// prepare builds the BVH of the group and of its descendants
+func (g *Group) prepare() {
+	for _, c := range g.children {
+		prepare(c)
+	}
+	g.update()
+}
+
+// update builds the BVH of the children, if their bounds changed since
+// it was built.
+func (g *Group) update() {
+	bounds := make([][2]Point3, len(g.children))
+	changed := g.bvh == nil || len(g.bounds) != len(bounds)
+	for i, c := range g.children {
+		min, max := globalMinMax(c)
+		bounds[i] = [2]Point3{min, max}
+		if !changed && bounds[i] != g.bounds[i] {
+			changed = true
+		}
+	}
+	if changed {
+		g.bvh = newBVH(g.children)
+		g.bounds = bounds
+	}
+}
+
+// Intersect ...
+func (g *Group) Intersect(r Ray) *Hit {
+	// not rendered, or changed since
+	if g.bvh == nil || len(g.children) == 0 {
+		return nil
+	}
+	h := g.bvh.intersect(g.RayToLocal(r))
+	if h == nil {
+		return nil
+	}
+	h.toParent(&g.Transform)
+	return h
+}
+
+// MinMax returns the bounds of the children, in the group local coords
+func (g *Group) MinMax() (Point3, Point3) {
+	if len(g.children) == 0 {
+		return Point3{}, Point3{}
+	}
+	min, max := globalMinMax(g.children[0])
+	for _, c := range g.children[1:] {
+		cmin, cmax := globalMinMax(c)
+		min, max = minPoint(min, cmin), maxPoint(max, cmax)
+	}
+	return min, max
+}
+
+// WorldMatrix returns the matrix transforming the local coords of the
+// group to those of the root of its scene graph, through its ancestors.
+func (g *Group) WorldMatrix() Matrix4 {
+	m := g.Matrix()
+	for p := g.parent; p != nil; p = p.parent {
+		m = p.Matrix().MulM(m)
+	}
+	return m
+}
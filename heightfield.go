@@ -0,0 +1,359 @@
+package ray
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"os"
+)
+
+// Heightfield is a terrain made of a grid of heights, spanning the
+// square from (-1, -1) to (1, 1) in the xz plane, the heights being
+// along y. Sample (i, j) is at x = -1 + 2*i/(nx-1), z = -1 + 2*j/(nz-1).
+// Each grid cell is split in 2 triangles, shaded with normals
+// interpolated from the grid. The mapping is the same as the plane's.
+// Like Plane, it has no inside.
+type Heightfield struct {
+	Transform
+	Surface
+	name    string
+	nx, nz  int
+	heights []float64
+	normals []Vector3
+	min     float64
+	max     float64
+	file    string            // image file the heights are loaded from
+	noise   *heightfieldNoise // noise the heights are generated from
+}
+
+// heightfieldNoise are the parameters of a generated heightfield
+type heightfieldNoise struct {
+	scale   float64
+	octaves int
+}
+
+// NewHeightfield creates a heightfield of nx by nz heights, given row by
+// row along x (heights[j*nx+i] is sample i, j).
+func NewHeightfield(nx, nz int, heights []float64) (*Heightfield, error) {
+	if nx < 2 || nz < 2 {
+		return nil, fmt.Errorf("heightfield: grid of %dx%d, at least 2x2 is needed", nx, nz)
+	}
+	if len(heights) != nx*nz {
+		return nil, fmt.Errorf("heightfield: %d heights for a grid of %dx%d", len(heights), nx, nz)
+	}
+	hf := &Heightfield{
+		Transform: IDTransform,
+		Surface:   DefaultSurface,
+		nx:        nx,
+		nz:        nz,
+		heights:   heights,
+	}
+	hf.build()
+	return hf, nil
+}
+
+// LoadHeightfield loads a heightfield from a grayscale PNG or JPEG image
+// file, black being height 0 and white height 1.
+func LoadHeightfield(name string) (*Heightfield, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	hf, err := NewImageHeightfield(img)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	hf.file = name
+	return hf, nil
+}
+
+// NewImageHeightfield creates a heightfield of the luminance of an image,
+// of at least 2x2 pixels. The first row of the image is at z = -1.
+func NewImageHeightfield(img image.Image) (*Heightfield, error) {
+	bounds := img.Bounds()
+	nx, nz := bounds.Dx(), bounds.Dy()
+	heights := make([]float64, nx*nz)
+	for j := 0; j < nz; j++ {
+		for i := 0; i < nx; i++ {
+			r, g, b, _ := img.At(bounds.Min.X+i, bounds.Min.Y+j).RGBA()
+			heights[j*nx+i] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+		}
+	}
+	return NewHeightfield(nx, nz, heights)
+}
+
+// NewNoiseHeightfield creates a heightfield of nx by nz heights of fractal
+// Perlin noise, of given number of octaves, the features of the first
+// one being of size scale in local coords. Heights are between 0 and 1.
+func NewNoiseHeightfield(nx, nz int, scale float64, octaves int) (*Heightfield, error) {
+	if nx < 2 || nz < 2 {
+		return nil, fmt.Errorf("heightfield: grid of %dx%d, at least 2x2 is needed", nx, nz)
+	}
+	if scale <= 0 || octaves < 1 {
+		return nil, fmt.Errorf("heightfield: noise of scale %g and %d octaves", scale, octaves)
+	}
+	heights := make([]float64, nx*nz)
+	min, max := math.MaxFloat64, -math.MaxFloat64
+	for j := 0; j < nz; j++ {
+		for i := 0; i < nx; i++ {
+			p := Point3{-1 + 2*float64(i)/float64(nx-1), 0, -1 + 2*float64(j)/float64(nz-1)}
+			p = scalePoint(p, 1/scale)
+			h, f := 0.0, 1.0
+			for o := 0; o < octaves; o++ {
+				h += Noise3(scalePoint(p, f)) / f
+				f *= 2
+			}
+			heights[j*nx+i] = h
+			min, max = math.Min(min, h), math.Max(max, h)
+		}
+	}
+	if max > min {
+		for i := range heights {
+			heights[i] = (heights[i] - min) / (max - min)
+		}
+	}
+	hf, err := NewHeightfield(nx, nz, heights)
+	if err != nil {
+		return nil, err
+	}
+	hf.noise = &heightfieldNoise{scale: scale, octaves: octaves}
+	return hf, nil
+}
+
+// SetName ...
+func (hf *Heightfield) SetName(name string) {
+	hf.name = "heightfield:" + name
+}
+
+// Name returns the heightfield's name
+func (hf *Heightfield) Name() string {
+	return hf.name
+}
+
+// Surf ...
+func (hf *Heightfield) Surf() *Surface {
+	return &hf.Surface
+}
+
+// Size returns the number of samples along x and z
+func (hf *Heightfield) Size() (int, int) {
+	return hf.nx, hf.nz
+}
+
+// Heights returns the heights, row by row along x
+func (hf *Heightfield) Heights() []float64 {
+	return hf.heights
+}
+
+// Translate applies a translation to the heightfield
+func (hf *Heightfield) Translate(x, y, z float64) *Heightfield {
+	hf.Transform.Translate(x, y, z)
+	return hf
+}
+
+// RotateX applies a rotation around x-axis to the heightfield
+func (hf *Heightfield) RotateX(x float64) *Heightfield {
+	hf.Transform.RotateX(x)
+	return hf
+}
+
+// RotateY applies a rotation around y-axis to the heightfield
+func (hf *Heightfield) RotateY(y float64) *Heightfield {
+	hf.Transform.RotateY(y)
+	return hf
+}
+
+// RotateZ applies a rotation around z-axis to the heightfield
+func (hf *Heightfield) RotateZ(z float64) *Heightfield {
+	hf.Transform.RotateZ(z)
+	return hf
+}
+
+// Scale applies a scaling transform to the heightfield
+func (hf *Heightfield) Scale(x, y, z float64) *Heightfield {
+	hf.Transform.Scale(x, y, z)
+	return hf
+}
+
+// MinMax ...
+func (hf *Heightfield) MinMax() (Point3, Point3) {
+	return Point3{-1, hf.min, -1}, Point3{1, hf.max, 1}
+}
+
+// build computes the height range and the vertex normals, by central
+// differences of the heights.
+func (hf *Heightfield) build() {
+	hf.min, hf.max = math.MaxFloat64, -math.MaxFloat64
+	for _, h := range hf.heights {
+		hf.min, hf.max = math.Min(hf.min, h), math.Max(hf.max, h)
+	}
+	hf.normals = make([]Vector3, len(hf.heights))
+	dx, dz := 2/float64(hf.nx-1), 2/float64(hf.nz-1)
+	for j := 0; j < hf.nz; j++ {
+		for i := 0; i < hf.nx; i++ {
+			i0, i1 := clampIndex(i-1, hf.nx), clampIndex(i+1, hf.nx)
+			j0, j1 := clampIndex(j-1, hf.nz), clampIndex(j+1, hf.nz)
+			n := Vector3{
+				-(hf.at(i1, j) - hf.at(i0, j)) / (float64(i1-i0) * dx),
+				1,
+				-(hf.at(i, j1) - hf.at(i, j0)) / (float64(j1-j0) * dz),
+			}
+			n.Normalize()
+			hf.normals[j*hf.nx+i] = n
+		}
+	}
+}
+
+// at returns the height of sample i, j
+func (hf *Heightfield) at(i, j int) float64 {
+	return hf.heights[j*hf.nx+i]
+}
+
+// clampIndex clamps i to [0, n-1]
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
+
+// vertex returns sample i, j as a point in local coords
+func (hf *Heightfield) vertex(i, j int) Point3 {
+	return Point3{
+		-1 + 2*float64(i)/float64(hf.nx-1),
+		hf.at(i, j),
+		-1 + 2*float64(j)/float64(hf.nz-1),
+	}
+}
+
+// cellTriangles returns the vertex indices of the 2 triangles of cell
+// i, j, counter-clockwise when seen from above.
+func (hf *Heightfield) cellTriangles(i, j int) [2][3][2]int {
+	return [2][3][2]int{
+		{{i, j}, {i, j + 1}, {i + 1, j + 1}},
+		{{i, j}, {i + 1, j + 1}, {i + 1, j}},
+	}
+}
+
+// Intersect walks the grid cells crossed by the ray, from the nearest
+// one, until a triangle is hit.
+func (hf *Heightfield) Intersect(r Ray) *Hit {
+	locRay := hf.RayToLocal(r)
+	t0, t1, ok := boxSpan(Point3{-1, hf.min, -1}, Point3{1, hf.max, 1}, locRay)
+	if !ok || t1 < Epsilon {
+		return nil
+	}
+	t0 = math.Max(t0, 0)
+
+	// cell of the entry point, and ray parameters of the next cell
+	// boundaries along x and z (2D DDA)
+	var (
+		cell, step [2]int
+		next, dt   [2]float64
+	)
+	axes := [2]int{X, Z}
+	n := [2]int{hf.nx - 1, hf.nz - 1}
+	for k, a := range axes {
+		size := 2 / float64(n[k])
+		g := (locRay.pt[a] + t0*locRay.dir[a] + 1) / size
+		cell[k] = clampIndex(int(math.Floor(g)), n[k])
+		switch d := locRay.dir[a]; {
+		case d > 0:
+			step[k] = 1
+			dt[k] = size / d
+			next[k] = (-1 + float64(cell[k]+1)*size - locRay.pt[a]) / d
+		case d < 0:
+			step[k] = -1
+			dt[k] = -size / d
+			next[k] = (-1 + float64(cell[k])*size - locRay.pt[a]) / d
+		default:
+			next[k] = math.Inf(1)
+		}
+	}
+
+	tEnter := t0
+	for {
+		tExit := math.Min(math.Min(next[0], next[1]), t1)
+		// skip the cell if the ray is above or below its heights
+		i, j := cell[0], cell[1]
+		ya := locRay.pt[Y] + tEnter*locRay.dir[Y]
+		yb := locRay.pt[Y] + tExit*locRay.dir[Y]
+		lo := math.Min(math.Min(hf.at(i, j), hf.at(i+1, j)), math.Min(hf.at(i, j+1), hf.at(i+1, j+1)))
+		hi := math.Max(math.Max(hf.at(i, j), hf.at(i+1, j)), math.Max(hf.at(i, j+1), hf.at(i+1, j+1)))
+		if math.Max(ya, yb) >= lo-Epsilon && math.Min(ya, yb) <= hi+Epsilon {
+			best, tri := math.MaxFloat64, -1
+			var b1, b2 float64
+			for k, v := range hf.cellTriangles(i, j) {
+				t, u, w, ok := intersectTriangle(locRay,
+					hf.vertex(v[0][0], v[0][1]), hf.vertex(v[1][0], v[1][1]), hf.vertex(v[2][0], v[2][1]))
+				if ok && t < best {
+					best, tri, b1, b2 = t, k, u, w
+				}
+			}
+			if tri >= 0 {
+				return hf.hit(r, locRay, hf.cellTriangles(i, j)[tri], best, b1, b2)
+			}
+		}
+		if tExit >= t1 {
+			return nil
+		}
+		k := 0
+		if next[1] < next[0] {
+			k = 1
+		}
+		cell[k] += step[k]
+		if cell[k] < 0 || cell[k] >= n[k] {
+			return nil
+		}
+		tEnter = next[k]
+		next[k] += dt[k]
+	}
+}
+
+// hit fills the Hit for a ray hitting triangle v at barycentric coords b1, b2.
+func (hf *Heightfield) hit(r, locRay Ray, v [3][2]int, t, b1, b2 float64) *Hit {
+	var h Hit
+	h.globRay = r
+	h.locRay = locRay
+	b0 := 1 - b1 - b2
+	lp := Point3{
+		locRay.pt[X] + t*locRay.dir[X],
+		locRay.pt[Y] + t*locRay.dir[Y],
+		locRay.pt[Z] + t*locRay.dir[Z],
+	}
+	h.locNorm.pt = lp
+	a, b, c := hf.vertex(v[0][0], v[0][1]), hf.vertex(v[1][0], v[1][1]), hf.vertex(v[2][0], v[2][1])
+	gn := NewVec(a, b).Cross(NewVec(a, c))
+	n := func(k int) Vector3 { return hf.normals[v[k][1]*hf.nx+v[k][0]] }
+	h.locNorm.dir = n(0).Mult(b0).Add(n(1).Mult(b1)).Add(n(2).Mult(b2))
+
+	// same mapping as the plane, following the slope of the triangle
+	h.u = (lp[X] + 1) / 2
+	h.v = (1 - lp[Z]) / 2
+	h.setTangents(&hf.Transform,
+		Vector3{2, -2 * gn[X] / gn[Y], 0},
+		Vector3{0, 2 * gn[Z] / gn[Y], -2})
+
+	// the normals must face the ray, which may come from below
+	if gn.Dot(locRay.dir) > 0 {
+		gn.Reverse()
+		h.locNorm.dir.Reverse()
+	}
+	if h.locNorm.dir.Dot(locRay.dir) > 0 {
+		// interpolated normal facing away: fall back to the face normal
+		h.locNorm.dir = gn
+	}
+	h.globNorm.pt = hf.PointToGlobal(lp)
+	h.globNorm.dir = hf.NormalToGlobal(h.locNorm.dir)
+	h.globNorm.Normalize()
+	h.Surface = &hf.Surface
+	return &h
+}
@@ -2,9 +2,38 @@ package ray
 
 // Hit contains intersection informations: surface where hit, and incident and normal ray (both global and local)
 type Hit struct {
-	*Surface     // material properties
-	globRay  Ray // incident in scene coords
-	locRay   Ray // incident in object coords
-	locNorm  Ray // normal in object coords
-	globNorm Ray // normal in scene coords
+	*Surface         // material properties
+	globRay  Ray     // incident in scene coords
+	locRay   Ray     // incident in object coords
+	locNorm  Ray     // normal in object coords
+	globNorm Ray     // normal in scene coords
+	inside   bool    // the ray hits the surface from inside the object
+	u, v     float64 // surface parameterization at hit point, in [0,1]
+	dpdu     Vector3 // dP/du tangent in scene coords
+	dpdv     Vector3 // dP/dv tangent in scene coords
+	locDpdu  Vector3 // dP/du tangent in object coords
+	locDpdv  Vector3 // dP/dv tangent in object coords
+	geoNorm  Vector3 // geometric normal in scene coords, globNorm may be perturbed
+	// size of the pixel on the surface in scene coords, 0 if unknown
+	footprint float64
+	// interpolated vertex color, if any
+	color *FloatColor
+}
+
+// setTangents sets the dP/du and dP/dv tangents, given in object coords.
+func (h *Hit) setTangents(t *Transform, dpdu, dpdv Vector3) {
+	h.locDpdu, h.locDpdv = dpdu, dpdv
+	h.dpdu = t.VectorToGlobal(dpdu)
+	h.dpdv = t.VectorToGlobal(dpdv)
+}
+
+// toParent transforms the hit from the local coords of t, those of a
+// child object, to the coords of the parent.
+func (h *Hit) toParent(t *Transform) {
+	h.globRay = t.RayToGlobal(h.globRay)
+	h.globNorm.pt = t.PointToGlobal(h.globNorm.pt)
+	h.globNorm.dir = t.NormalToGlobal(h.globNorm.dir)
+	h.globNorm.Normalize()
+	h.dpdu = t.VectorToGlobal(h.dpdu)
+	h.dpdv = t.VectorToGlobal(h.dpdv)
 }
@@ -0,0 +1,237 @@
+package ray
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"os"
+)
+
+// Filter is the filtering of an ImageTexture
+type Filter int
+
+const (
+	// FilterNearest takes the nearest texel
+	FilterNearest Filter = iota
+	// FilterBilinear interpolates the 4 nearest texels
+	FilterBilinear
+	// FilterMipmap interpolates bilinearly between the 2 mipmap levels
+	// matching the size of the pixel on the surface (trilinear filtering)
+	FilterMipmap
+)
+
+// Wrap is the addressing mode of an ImageTexture, for UV outside of [0,1]
+type Wrap int
+
+const (
+	// WrapRepeat tiles the image
+	WrapRepeat Wrap = iota
+	// WrapClamp repeats the border texels
+	WrapClamp
+	// WrapMirror tiles the image, flipping every other tile
+	WrapMirror
+)
+
+var (
+	filterNames = [...]string{"nearest", "bilinear", "mipmap"}
+	wrapNames   = [...]string{"repeat", "clamp", "mirror"}
+)
+
+func (f Filter) String() string {
+	if f < 0 || int(f) >= len(filterNames) {
+		return fmt.Sprintf("Filter(%d)", int(f))
+	}
+	return filterNames[f]
+}
+
+func (w Wrap) String() string {
+	if w < 0 || int(w) >= len(wrapNames) {
+		return fmt.Sprintf("Wrap(%d)", int(w))
+	}
+	return wrapNames[w]
+}
+
+// parseFilter returns the filter given its name
+func parseFilter(name string) (Filter, error) {
+	for i, n := range filterNames {
+		if n == name {
+			return Filter(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown texture filter %q", name)
+}
+
+// parseWrap returns the wrap mode given its name
+func parseWrap(name string) (Wrap, error) {
+	for i, n := range wrapNames {
+		if n == name {
+			return Wrap(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown texture wrap %q", name)
+}
+
+// ImageTexture maps an image on the surface, using the UV of the hit.
+// (0,0) is the bottom left of the image and (1,1) the top right.
+type ImageTexture struct {
+	Filter Filter
+	Wrap   Wrap
+	UScale float64 // number of times the image is repeated along u
+	VScale float64 // number of times the image is repeated along v
+	levels []mipLevel
+	name   string
+}
+
+// mipLevel is one level of the mipmap pyramid, level 0 is the full image.
+type mipLevel struct {
+	w   int
+	h   int
+	pix []FloatColor
+}
+
+// LoadImageTexture loads a PNG or JPEG image file as texture.
+func LoadImageTexture(name string) (*ImageTexture, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	if img.Bounds().Empty() {
+		return nil, fmt.Errorf("%s: empty image", name)
+	}
+	t := NewImageTexture(img)
+	t.name = name
+	return t, nil
+}
+
+// NewImageTexture creates a bilinear, repeated texture from a non empty image.
+func NewImageTexture(img image.Image) *ImageTexture {
+	bounds := img.Bounds()
+	l := mipLevel{w: bounds.Dx(), h: bounds.Dy(), pix: make([]FloatColor, bounds.Dx()*bounds.Dy())}
+	for y := 0; y < l.h; y++ {
+		for x := 0; x < l.w; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			l.pix[y*l.w+x] = FloatColor{
+				R: float64(r) / 0xffff,
+				G: float64(g) / 0xffff,
+				B: float64(b) / 0xffff,
+			}
+		}
+	}
+	t := &ImageTexture{Filter: FilterBilinear, UScale: 1, VScale: 1, levels: []mipLevel{l}}
+	for l.w > 1 || l.h > 1 {
+		l = l.half()
+		t.levels = append(t.levels, l)
+	}
+	return t
+}
+
+// half returns the next mipmap level, by averaging 2x2 texels.
+func (l mipLevel) half() mipLevel {
+	n := mipLevel{w: (l.w + 1) / 2, h: (l.h + 1) / 2}
+	n.pix = make([]FloatColor, n.w*n.h)
+	for y := 0; y < n.h; y++ {
+		y0, y1 := 2*y, 2*y+1
+		if y1 >= l.h {
+			y1 = y0
+		}
+		for x := 0; x < n.w; x++ {
+			x0, x1 := 2*x, 2*x+1
+			if x1 >= l.w {
+				x1 = x0
+			}
+			c := l.pix[y0*l.w+x0]
+			c.Add(l.pix[y0*l.w+x1])
+			c.Add(l.pix[y1*l.w+x0])
+			c.Add(l.pix[y1*l.w+x1])
+			n.pix[y*n.w+x] = c.MulF(0.25)
+		}
+	}
+	return n
+}
+
+// ColorAt implements Texture
+func (t *ImageTexture) ColorAt(h *Hit) FloatColor {
+	u, v := h.u*t.UScale, h.v*t.VScale
+	switch t.Filter {
+	case FilterNearest:
+		return t.nearest(0, u, v)
+	case FilterMipmap:
+		lod := t.lod(h)
+		l0 := int(math.Floor(lod))
+		if l0 >= len(t.levels)-1 {
+			return t.bilinear(len(t.levels)-1, u, v)
+		}
+		f := lod - float64(l0)
+		c := t.bilinear(l0, u, v).MulF(1 - f)
+		return c.Add(t.bilinear(l0+1, u, v).MulF(f))
+	}
+	return t.bilinear(0, u, v)
+}
+
+// lod returns the mipmap level matching the hit footprint, 0 if unknown.
+func (t *ImageTexture) lod(h *Hit) float64 {
+	lu, lv := h.dpdu.Norm(), h.dpdv.Norm()
+	if h.footprint <= 0 || lu < Epsilon || lv < Epsilon {
+		return 0
+	}
+	// texels covered by the footprint along u and v
+	du := h.footprint / lu * t.UScale * float64(t.levels[0].w)
+	dv := h.footprint / lv * t.VScale * float64(t.levels[0].h)
+	lod := math.Log2(math.Max(du, dv))
+	if lod < 0 {
+		return 0
+	}
+	return lod
+}
+
+func (t *ImageTexture) nearest(level int, u, v float64) FloatColor {
+	l := &t.levels[level]
+	x := int(math.Floor(u * float64(l.w)))
+	y := int(math.Floor((1 - v) * float64(l.h)))
+	return l.texel(t.Wrap, x, y)
+}
+
+func (t *ImageTexture) bilinear(level int, u, v float64) FloatColor {
+	l := &t.levels[level]
+	// texel centers are at half integer coords
+	fx := u*float64(l.w) - 0.5
+	fy := (1-v)*float64(l.h) - 0.5
+	x0, y0 := math.Floor(fx), math.Floor(fy)
+	ax, ay := fx-x0, fy-y0
+	x, y := int(x0), int(y0)
+	c := l.texel(t.Wrap, x, y).MulF((1 - ax) * (1 - ay))
+	c.Add(l.texel(t.Wrap, x+1, y).MulF(ax * (1 - ay)))
+	c.Add(l.texel(t.Wrap, x, y+1).MulF((1 - ax) * ay))
+	c.Add(l.texel(t.Wrap, x+1, y+1).MulF(ax * ay))
+	return c
+}
+
+func (l *mipLevel) texel(w Wrap, x, y int) FloatColor {
+	return l.pix[wrap(w, y, l.h)*l.w+wrap(w, x, l.w)]
+}
+
+// wrap returns the texel index in [0,n) for index i, following the addressing mode.
+func wrap(w Wrap, i, n int) int {
+	switch w {
+	case WrapClamp:
+		if i < 0 {
+			return 0
+		}
+		if i >= n {
+			return n - 1
+		}
+		return i
+	case WrapMirror:
+		i = ((i % (2 * n)) + 2*n) % (2 * n)
+		if i >= n {
+			return 2*n - 1 - i
+		}
+		return i
+	}
+	return ((i % n) + n) % n
+}
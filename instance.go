@@ -0,0 +1,114 @@
+package ray
+
+// Instance places a shared object in the scene with its own transform,
+// the object's transform being applied first. The object, which may be
+// a mesh or a Group of many objects, is not copied, so it can be
+// referenced by many instances at the cost of one. If Surface is set,
+// it overrides the surfaces of the object.
+type Instance struct {
+	Transform
+	name    string
+	Object  Object
+	Surface *Surface
+}
+
+// NewInstance creates an instance of o
+func NewInstance(o Object) *Instance {
+	return &Instance{
+		Transform: IDTransform,
+		Object:    o,
+	}
+}
+
+// SetName ...
+func (in *Instance) SetName(name string) {
+	in.name = "instance:" + name
+}
+
+// Name returns the instance's name
+func (in *Instance) Name() string {
+	return in.name
+}
+
+// Translate applies a translation to the instance
+func (in *Instance) Translate(x, y, z float64) *Instance {
+	in.Transform.Translate(x, y, z)
+	return in
+}
+
+// RotateX applies a rotation around x-axis to the instance
+func (in *Instance) RotateX(x float64) *Instance {
+	in.Transform.RotateX(x)
+	return in
+}
+
+// RotateY applies a rotation around y-axis to the instance
+func (in *Instance) RotateY(y float64) *Instance {
+	in.Transform.RotateY(y)
+	return in
+}
+
+// RotateZ applies a rotation around z-axis to the instance
+func (in *Instance) RotateZ(z float64) *Instance {
+	in.Transform.RotateZ(z)
+	return in
+}
+
+// Scale applies a scaling transform to the instance
+func (in *Instance) Scale(x, y, z float64) *Instance {
+	in.Transform.Scale(x, y, z)
+	return in
+}
+
+// Intersect ...
+func (in *Instance) Intersect(r Ray) *Hit {
+	h := in.Object.Intersect(in.RayToLocal(r))
+	if h == nil {
+		return nil
+	}
+	in.hit(h)
+	return h
+}
+
+// Spans implements Solid, when the object is a solid. Otherwise, the
+// instance is empty.
+func (in *Instance) Spans(r Ray) []Span {
+	s, ok := in.Object.(Solid)
+	if !ok {
+		return nil
+	}
+	spans := s.Spans(in.RayToLocal(r))
+	for _, sp := range spans {
+		in.hit(sp.H0)
+		in.hit(sp.H1)
+	}
+	return spans
+}
+
+// isSolid returns true if the instanced object is a solid
+func (in *Instance) isSolid() bool {
+	if i, ok := in.Object.(*Instance); ok {
+		return i.isSolid()
+	}
+	_, ok := in.Object.(Solid)
+	return ok
+}
+
+func (in *Instance) prepare() {
+	prepare(in.Object)
+}
+
+// hit converts a hit of the object to the instance's parent coords
+func (in *Instance) hit(h *Hit) {
+	h.toParent(&in.Transform)
+	if in.Surface != nil {
+		// the vertex colors of a mesh are part of its surface
+		h.Surface = in.Surface
+		h.color = nil
+	}
+}
+
+// MinMax returns the bounds of the object, in the instance local coords
+func (in *Instance) MinMax() (Point3, Point3) {
+	return globalMinMax(in.Object)
+}
@@ -1,5 +1,7 @@
 package ray
 
+import "math"
+
 type Light interface {
 	RayToLight(Point3) Ray
 	Color(Ray) FloatColor
@@ -8,12 +10,13 @@ type Light interface {
 
 type PointLight struct {
 	Transform
-	c   FloatColor
-	sun bool
+	c           FloatColor
+	sun         bool
+	Attenuation Attenuation
 }
 
 func NewPointLight(c FloatColor) *PointLight {
-	return &PointLight{Transform: IDTransform, c: c}
+	return &PointLight{Transform: IDTransform, c: c, Attenuation: DefaultAttenuation}
 }
 
 func (p *PointLight) RayToLight(pt Point3) Ray {
@@ -38,3 +41,163 @@ func (p *PointLight) Sun() bool {
 func (p *PointLight) SetSun(sun bool) {
 	p.sun = sun
 }
+
+func (p *PointLight) attenuation() *Attenuation {
+	return &p.Attenuation
+}
+
+// distantLight is implemented by the lights at infinite distance, whose
+// RayToLight only gives the direction.
+type distantLight interface {
+	distant() bool
+}
+
+// isDistant returns true if li is at infinite distance
+func isDistant(li Light) bool {
+	d, ok := li.(distantLight)
+	return ok && d.distant()
+}
+
+// DirectionalLight is a light at infinite distance, such as the sun,
+// whose rays are parallel. It shines along the -y axis, rotated by its
+// transform, and is not attenuated by distance.
+type DirectionalLight struct {
+	Transform
+	c FloatColor
+}
+
+// NewDirectionalLight creates a directional light of color c, shining down
+func NewDirectionalLight(c FloatColor) *DirectionalLight {
+	return &DirectionalLight{Transform: IDTransform, c: c}
+}
+
+// Direction returns the direction of the light rays
+func (d *DirectionalLight) Direction() Vector3 {
+	v := d.VectorToGlobal(Vector3{0, -1, 0})
+	v.Normalize()
+	return v
+}
+
+// RayToLight returns the unit ray from pt towards the light
+func (d *DirectionalLight) RayToLight(pt Point3) Ray {
+	dir := d.Direction()
+	dir.Reverse()
+	return Ray{pt: pt, dir: dir}
+}
+
+// Color ...
+func (d *DirectionalLight) Color(r Ray) FloatColor {
+	return d.c
+}
+
+// Sun ...
+func (d *DirectionalLight) Sun() bool {
+	return true
+}
+
+func (d *DirectionalLight) distant() bool {
+	return true
+}
+
+// RotateX applies a rotation around x-axis to the light
+func (d *DirectionalLight) RotateX(x float64) *DirectionalLight {
+	d.Transform.RotateX(x)
+	return d
+}
+
+// RotateY applies a rotation around y-axis to the light
+func (d *DirectionalLight) RotateY(y float64) *DirectionalLight {
+	d.Transform.RotateY(y)
+	return d
+}
+
+// RotateZ applies a rotation around z-axis to the light
+func (d *DirectionalLight) RotateZ(z float64) *DirectionalLight {
+	d.Transform.RotateZ(z)
+	return d
+}
+
+// SpotLight is a point light at origin shining along the -y axis, both
+// placed by its transform, in a cone. The light is full inside the Inner
+// cone, fades smoothly to none at the Outer cone, and is none outside.
+// The cone angles are half apertures, in radians.
+type SpotLight struct {
+	Transform
+	c           FloatColor
+	Inner       float64
+	Outer       float64
+	Attenuation Attenuation
+}
+
+// NewSpotLight creates a spot light of color c, of given cone angles
+func NewSpotLight(c FloatColor, inner, outer float64) *SpotLight {
+	return &SpotLight{
+		Transform:   IDTransform,
+		c:           c,
+		Inner:       inner,
+		Outer:       outer,
+		Attenuation: DefaultAttenuation,
+	}
+}
+
+// Direction returns the direction of the spot axis
+func (s *SpotLight) Direction() Vector3 {
+	v := s.VectorToGlobal(Vector3{0, -1, 0})
+	v.Normalize()
+	return v
+}
+
+// RayToLight ...
+func (s *SpotLight) RayToLight(pt Point3) Ray {
+	return NewRay(pt, s.PointToGlobal(Origin))
+}
+
+// Color returns the color of the light received along r, the ray
+// towards the light, according to its angle with the spot axis.
+func (s *SpotLight) Color(r Ray) FloatColor {
+	dir := r.dir
+	dir.Normalize()
+	cos := -dir.Dot(s.Direction())
+	cosIn, cosOut := math.Cos(s.Inner), math.Cos(s.Outer)
+	switch {
+	case cos >= cosIn:
+		return s.c
+	case cos <= cosOut:
+		return FloatColor{}
+	}
+	f := (cos - cosOut) / (cosIn - cosOut)
+	return s.c.MulF(f * f * (3 - 2*f))
+}
+
+// Sun ...
+func (s *SpotLight) Sun() bool {
+	return false
+}
+
+func (s *SpotLight) attenuation() *Attenuation {
+	return &s.Attenuation
+}
+
+// Translate applies a translation to the light
+func (s *SpotLight) Translate(x, y, z float64) *SpotLight {
+	s.Transform.Translate(x, y, z)
+	return s
+}
+
+// RotateX applies a rotation around x-axis to the light
+func (s *SpotLight) RotateX(x float64) *SpotLight {
+	s.Transform.RotateX(x)
+	return s
+}
+
+// RotateY applies a rotation around y-axis to the light
+func (s *SpotLight) RotateY(y float64) *SpotLight {
+	s.Transform.RotateY(y)
+	return s
+}
+
+// RotateZ applies a rotation around z-axis to the light
+func (s *SpotLight) RotateZ(z float64) *SpotLight {
+	s.Transform.RotateZ(z)
+	return s
+}
@@ -2,6 +2,7 @@ package ray
 
 import (
 	"math"
+	"sort"
 )
 
 //var Epsilon = math.Nextafter(1.0, 2.0) - 1.0
@@ -243,3 +244,228 @@ func Transpose(m Matrix4) Matrix4 {
 		{m[0][3], m[1][3], m[2][3], m[3][3]},
 	}
 }
+
+// Inverse returns the inverse of the matrix, computed by Gauss-Jordan
+// elimination with partial pivoting, and false if it is not invertible.
+func (a Matrix4) Inverse() (Matrix4, bool) {
+	inv := ID()
+	for c := 0; c < 4; c++ {
+		// find pivot row
+		p := c
+		for r := c + 1; r < 4; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[p][c]) {
+				p = r
+			}
+		}
+		if isNul(a[p][c]) {
+			return Matrix4{}, false
+		}
+		a[c], a[p] = a[p], a[c]
+		inv[c], inv[p] = inv[p], inv[c]
+		// normalize pivot row
+		f := 1 / a[c][c]
+		for k := 0; k < 4; k++ {
+			a[c][k] *= f
+			inv[c][k] *= f
+		}
+		// eliminate column c from other rows
+		for r := 0; r < 4; r++ {
+			if r == c || a[r][c] == 0 {
+				continue
+			}
+			f := a[r][c]
+			for k := 0; k < 4; k++ {
+				a[r][k] -= f * a[c][k]
+				inv[r][k] -= f * inv[c][k]
+			}
+		}
+	}
+	return inv, true
+}
+
+// SolveQuadratic returns the real roots of a*x² + b*x + c = 0,
+// in increasing order.
+func SolveQuadratic(a, b, c float64) []float64 {
+	if isNul(a) {
+		if isNul(b) {
+			return nil
+		}
+		return []float64{-c / b}
+	}
+	delta := b*b - 4*a*c
+	switch {
+	case math.Abs(delta) <= 1e-12*math.Max(b*b, math.Abs(4*a*c)):
+		// double root, or almost: don't lose it to rounding errors
+		return []float64{-b / (2 * a)}
+	case delta < 0:
+		return nil
+	}
+	// avoid the cancellation of -b + sqrt(delta)
+	q := -(b + math.Copysign(math.Sqrt(delta), b)) / 2
+	x1, x2 := q/a, c/q
+	if q == 0 {
+		x2 = -x1
+	}
+	if x1 > x2 {
+		x1, x2 = x2, x1
+	}
+	return []float64{x1, x2}
+}
+
+// SolveCubic returns the real roots of a*x³ + b*x² + c*x + d = 0,
+// in increasing order.
+func SolveCubic(a, b, c, d float64) []float64 {
+	if isNul(a) {
+		return SolveQuadratic(b, c, d)
+	}
+	roots := solveNormedCubic(b/a, c/a, d/a)
+	for i := range roots {
+		roots[i] = polish(roots[i], []float64{a, b, c, d})
+	}
+	sort.Float64s(roots)
+	return roots
+}
+
+// solveNormedCubic returns the real roots of x³ + a*x² + b*x + c = 0
+// (Cardano).
+func solveNormedCubic(a, b, c float64) []float64 {
+	// substitute x = y - a/3 to get y³ + 3p*y + 2q = 0
+	sqA := a * a
+	p := (-sqA/3 + b) / 3
+	q := (2*a*sqA/27 - a*b/3 + c) / 2
+	cbP := p * p * p
+	d := q*q + cbP
+	var roots []float64
+	// tolerances relative to the terms of d, as the scale of the roots
+	// can be anything
+	tol := 1e-14 * math.Max(q*q, math.Abs(cbP))
+	switch {
+	case math.Abs(d) <= tol:
+		if q*q <= tol {
+			roots = []float64{0}
+		} else {
+			u := math.Cbrt(-q)
+			roots = []float64{2 * u, -u}
+		}
+	case d < 0:
+		// 3 real roots
+		phi := math.Acos(math.Max(-1, math.Min(1, -q/math.Sqrt(-cbP)))) / 3
+		t := 2 * math.Sqrt(-p)
+		roots = []float64{
+			t * math.Cos(phi),
+			-t * math.Cos(phi+math.Pi/3),
+			-t * math.Cos(phi-math.Pi/3),
+		}
+	default:
+		sqD := math.Sqrt(d)
+		roots = []float64{math.Cbrt(sqD-q) - math.Cbrt(sqD+q)}
+	}
+	for i := range roots {
+		roots[i] -= a / 3
+	}
+	return roots
+}
+
+// SolveQuartic returns the real roots of a*x⁴ + b*x³ + c*x² + d*x + e = 0,
+// in increasing order. The roots found by Ferrari's method are refined
+// by Newton iterations, which fixes most of its loss of precision.
+func SolveQuartic(a, b, c, d, e float64) []float64 {
+	if isNul(a) {
+		return SolveCubic(b, c, d, e)
+	}
+	A, B, C, D := b/a, c/a, d/a, e/a
+	// substitute x = y - A/4 to get y⁴ + p*y² + q*y + r = 0
+	sqA := A * A
+	p := -3*sqA/8 + B
+	q := sqA*A/8 - A*B/2 + C
+	r := -3*sqA*sqA/256 + sqA*B/16 - A*C/4 + D
+	coefs := []float64{a, b, c, d, e}
+	var roots []float64
+	if r == 0 {
+		// y(y³ + p*y + q) = 0
+		roots = append(solveNormedCubic(0, p, q), 0)
+	} else {
+		// a root z of the resolvent cubic splits the quartic in 2 quadratics
+		// (y² + z)² = (v*y - w)², with v² = 2z - p, w² = z² - r and
+		// 2vw = q. For the largest one, v and w are real.
+		resolvent := []float64{1, -p / 2, -r, r*p/2 - q*q/8}
+		z := math.Inf(-1)
+		for _, s := range solveNormedCubic(resolvent[1], resolvent[2], resolvent[3]) {
+			z = math.Max(z, s)
+		}
+		z = polish(z, resolvent)
+		// compute the largest of v² and w², which is the most accurate,
+		// and derive the other one from q
+		var v, w float64
+		if v2, w2 := 2*z-p, z*z-r; v2 >= w2 {
+			if v = math.Sqrt(math.Max(0, v2)); v > 0 {
+				w = q / (2 * v)
+			}
+		} else {
+			w = math.Sqrt(w2)
+			v = q / (2 * w)
+		}
+		for _, f := range [][2]float64{{-v, z + w}, {v, z - w}} {
+			ys := SolveQuadratic(1, f[0], f[1])
+			// no real roots may be a double root lost to rounding errors,
+			// if the quartic is nul at the quadratic extremum
+			if y := -f[0] / 2; len(ys) == 0 && isPolyRoot(y-A/4, coefs) {
+				ys = []float64{y}
+			}
+			roots = append(roots, ys...)
+		}
+	}
+	for i := range roots {
+		roots[i] = polish(roots[i]-A/4, coefs)
+	}
+	sort.Float64s(roots)
+	return roots
+}
+
+// polish refines a root of the polynomial of given coefficients (highest
+// degree first) with a few Newton iterations, damped to always improve it.
+func polish(x float64, coefs []float64) float64 {
+	f, df := evalPoly(x, coefs)
+	for i := 0; i < 8 && f != 0 && df != 0; i++ {
+		dx := f / df
+		nx, nf, ndf := x, f, df
+		for j := 0; j < 4; j++ {
+			nx = x - dx
+			nf, ndf = evalPoly(nx, coefs)
+			if math.Abs(nf) < math.Abs(f) {
+				break
+			}
+			dx /= 2
+		}
+		if !(math.Abs(nf) < math.Abs(f)) {
+			break
+		}
+		x, f, df = nx, nf, ndf
+	}
+	return x
+}
+
+// isPolyRoot returns true if the polynomial of given coefficients is nul
+// at x, up to the rounding errors of its evaluation.
+func isPolyRoot(x float64, coefs []float64) bool {
+	f, _ := evalPoly(x, coefs)
+	bound := 0.0
+	for _, c := range coefs {
+		bound = bound*math.Abs(x) + math.Abs(c)
+	}
+	return math.Abs(f) <= 64*eps*bound
+}
+
+// eps is the machine epsilon
+const eps = 0x1p-52
+
+// evalPoly returns the value and derivative at x of the polynomial of
+// given coefficients, highest degree first (Horner).
+func evalPoly(x float64, coefs []float64) (float64, float64) {
+	f, df := 0.0, 0.0
+	for _, c := range coefs {
+		df = df*x + f
+		f = f*x + c
+	}
+	return f, df
+}
@@ -0,0 +1,451 @@
+package ray
+
+import (
+	"fmt"
+	"math"
+)
+
+// TriangleMesh is a set of triangles sharing a vertex buffer, in local coords.
+// Faces are triplets of vertex indices, counter-clockwise when seen from
+// outside. Optional per-vertex normals give smooth shading, and per-vertex
+// UVs the texture mapping. The triangles are indexed by a BVH built when
+// the mesh is created, so the vertices and faces must not be modified.
+type TriangleMesh struct {
+	Transform
+	Surface
+	name     string
+	vertices []Point3
+	faces    [][3]int
+	normals  []Vector3
+	uvs      [][2]float64
+	colors   []FloatColor
+	nodes    []meshNode
+	tris     []int // face indices, ordered by BVH leaves
+}
+
+// meshNode is a node of the flattened BVH of a mesh. Inner nodes have
+// their children at index left and left+1, leaves have count > 0 faces
+// starting at index first of tris.
+type meshNode struct {
+	min   Point3
+	max   Point3
+	left  int32
+	first int32
+	count int32
+}
+
+// NewTriangleMesh creates a mesh of faces made of vertices indices.
+func NewTriangleMesh(vertices []Point3, faces [][3]int) (*TriangleMesh, error) {
+	for i, f := range faces {
+		for _, v := range f {
+			if v < 0 || v >= len(vertices) {
+				return nil, fmt.Errorf("mesh: face %d: vertex index %d out of range", i, v)
+			}
+		}
+	}
+	m := &TriangleMesh{
+		Transform: IDTransform,
+		Surface:   DefaultSurface,
+		vertices:  vertices,
+		faces:     faces,
+	}
+	m.build()
+	return m, nil
+}
+
+// SetName ...
+func (m *TriangleMesh) SetName(name string) {
+	m.name = "mesh:" + name
+}
+
+// Name returns the mesh's name
+func (m *TriangleMesh) Name() string {
+	return m.name
+}
+
+// Surf ...
+func (m *TriangleMesh) Surf() *Surface {
+	return &m.Surface
+}
+
+// Vertices returns the vertex buffer
+func (m *TriangleMesh) Vertices() []Point3 {
+	return m.vertices
+}
+
+// Faces returns the faces
+func (m *TriangleMesh) Faces() [][3]int {
+	return m.faces
+}
+
+// Normals returns the per-vertex normals, or nil if flat shaded
+func (m *TriangleMesh) Normals() []Vector3 {
+	return m.normals
+}
+
+// UVs returns the per-vertex UVs, or nil
+func (m *TriangleMesh) UVs() [][2]float64 {
+	return m.uvs
+}
+
+// SetNormals sets per-vertex normals, interpolated for smooth shading.
+func (m *TriangleMesh) SetNormals(normals []Vector3) error {
+	if normals != nil && len(normals) != len(m.vertices) {
+		return fmt.Errorf("mesh: %d normals for %d vertices", len(normals), len(m.vertices))
+	}
+	m.normals = normals
+	return nil
+}
+
+// SetUVs sets per-vertex texture coords.
+func (m *TriangleMesh) SetUVs(uvs [][2]float64) error {
+	if uvs != nil && len(uvs) != len(m.vertices) {
+		return fmt.Errorf("mesh: %d uvs for %d vertices", len(uvs), len(m.vertices))
+	}
+	m.uvs = uvs
+	return nil
+}
+
+// Colors returns the per-vertex colors, or nil
+func (m *TriangleMesh) Colors() []FloatColor {
+	return m.colors
+}
+
+// SetColors sets per-vertex colors, interpolated over the faces. They
+// replace the surface Color, but not its color texture.
+func (m *TriangleMesh) SetColors(colors []FloatColor) error {
+	if colors != nil && len(colors) != len(m.vertices) {
+		return fmt.Errorf("mesh: %d colors for %d vertices", len(colors), len(m.vertices))
+	}
+	m.colors = colors
+	return nil
+}
+
+// SmoothNormals computes per-vertex normals by averaging the normals
+// of the faces sharing the vertex, weighted by their area.
+func (m *TriangleMesh) SmoothNormals() {
+	normals := make([]Vector3, len(m.vertices))
+	for _, f := range m.faces {
+		n := m.faceNormal(f)
+		for _, v := range f {
+			normals[v] = normals[v].Add(n)
+		}
+	}
+	for i := range normals {
+		if normals[i].Norm() > Epsilon {
+			normals[i].Normalize()
+		}
+	}
+	m.normals = normals
+}
+
+// faceNormal returns the face normal, of norm twice its area
+func (m *TriangleMesh) faceNormal(f [3]int) Vector3 {
+	a, b, c := m.vertices[f[0]], m.vertices[f[1]], m.vertices[f[2]]
+	return NewVec(a, b).Cross(NewVec(a, c))
+}
+
+// Translate applies a translation to the mesh
+func (m *TriangleMesh) Translate(x, y, z float64) *TriangleMesh {
+	m.Transform.Translate(x, y, z)
+	return m
+}
+
+// RotateX applies a rotation around x-axis to the mesh
+func (m *TriangleMesh) RotateX(x float64) *TriangleMesh {
+	m.Transform.RotateX(x)
+	return m
+}
+
+// RotateY applies a rotation around y-axis to the mesh
+func (m *TriangleMesh) RotateY(y float64) *TriangleMesh {
+	m.Transform.RotateY(y)
+	return m
+}
+
+// RotateZ applies a rotation around z-axis to the mesh
+func (m *TriangleMesh) RotateZ(z float64) *TriangleMesh {
+	m.Transform.RotateZ(z)
+	return m
+}
+
+// Scale applies a scaling transform to the mesh
+func (m *TriangleMesh) Scale(x, y, z float64) *TriangleMesh {
+	m.Transform.Scale(x, y, z)
+	return m
+}
+
+// MinMax ...
+func (m *TriangleMesh) MinMax() (Point3, Point3) {
+	if len(m.nodes) == 0 {
+		return Point3{}, Point3{}
+	}
+	return m.nodes[0].min, m.nodes[0].max
+}
+
+// Intersect finds the closest triangle hit by the ray.
+func (m *TriangleMesh) Intersect(r Ray) *Hit {
+	if len(m.nodes) == 0 {
+		return nil
+	}
+	locRay := m.RayToLocal(r)
+	inv := inverseDir(locRay)
+	face := -1
+	var tmin, b1, b2 float64
+	tmin = math.MaxFloat64
+
+	stack := make([]int32, 1, 64)
+	for len(stack) > 0 {
+		n := &m.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if _, ok := hitBox(n.min, n.max, locRay, inv, tmin); !ok {
+			continue
+		}
+		if n.count > 0 {
+			for _, f := range m.tris[n.first : n.first+n.count] {
+				fv := m.faces[f]
+				t, u, v, ok := intersectTriangle(locRay, m.vertices[fv[0]], m.vertices[fv[1]], m.vertices[fv[2]])
+				if ok && t < tmin {
+					tmin, b1, b2, face = t, u, v, f
+				}
+			}
+			continue
+		}
+		stack = append(stack, n.left, n.left+1)
+	}
+	if face < 0 {
+		return nil
+	}
+	return m.hit(r, locRay, face, tmin, b1, b2)
+}
+
+// hit fills the Hit for a ray hitting a face at barycentric coords b1, b2.
+func (m *TriangleMesh) hit(r, locRay Ray, face int, t, b1, b2 float64) *Hit {
+	var h Hit
+	h.globRay = r
+	h.locRay = locRay
+	f := m.faces[face]
+	b0 := 1 - b1 - b2
+	h.locNorm.pt = Point3{
+		locRay.pt[X] + t*locRay.dir[X],
+		locRay.pt[Y] + t*locRay.dir[Y],
+		locRay.pt[Z] + t*locRay.dir[Z],
+	}
+	gn := m.faceNormal(f)
+	if m.normals != nil {
+		h.locNorm.dir = m.normals[f[0]].Mult(b0).Add(m.normals[f[1]].Mult(b1)).Add(m.normals[f[2]].Mult(b2))
+	} else {
+		h.locNorm.dir = gn
+	}
+
+	e1 := NewVec(m.vertices[f[0]], m.vertices[f[1]])
+	e2 := NewVec(m.vertices[f[0]], m.vertices[f[2]])
+	if m.uvs != nil {
+		uv0, uv1, uv2 := m.uvs[f[0]], m.uvs[f[1]], m.uvs[f[2]]
+		h.u = b0*uv0[0] + b1*uv1[0] + b2*uv2[0]
+		h.v = b0*uv0[1] + b1*uv1[1] + b2*uv2[1]
+		// solve e1 = du1*dpdu + dv1*dpdv, e2 = du2*dpdu + dv2*dpdv
+		du1, dv1 := uv1[0]-uv0[0], uv1[1]-uv0[1]
+		du2, dv2 := uv2[0]-uv0[0], uv2[1]-uv0[1]
+		det := du1*dv2 - dv1*du2
+		if math.Abs(det) > Epsilon {
+			inv := 1 / det
+			h.setTangents(&m.Transform,
+				e1.Mult(dv2*inv).Sub(e2.Mult(dv1*inv)),
+				e2.Mult(du1*inv).Sub(e1.Mult(du2*inv)))
+		} else {
+			h.setTangents(&m.Transform, e1, e2)
+		}
+	} else {
+		h.u, h.v = b1, b2
+		h.setTangents(&m.Transform, e1, e2)
+	}
+
+	if m.colors != nil {
+		c := m.colors[f[0]].MulF(b0)
+		c.Add(m.colors[f[1]].MulF(b1))
+		c.Add(m.colors[f[2]].MulF(b2))
+		h.color = &c
+	}
+
+	// the normals must face the ray, which comes from inside if it
+	// goes the same way as the geometric normal
+	if gn.Dot(locRay.dir) > 0 {
+		h.inside = true
+		gn.Reverse()
+		h.locNorm.dir.Reverse()
+	}
+	if h.locNorm.dir.Dot(locRay.dir) > 0 {
+		// interpolated normal facing away: fall back to the face normal
+		h.locNorm.dir = gn
+	}
+	h.globNorm = m.RayToGlobal(h.locNorm)
+	h.globNorm.Normalize()
+	h.Surface = &m.Surface
+	return &h
+}
+
+// meshLeafSize is the max number of faces in a mesh BVH leaf
+const meshLeafSize = 4
+
+// meshBins is the number of bins to evaluate the SAH splits
+const meshBins = 16
+
+// build builds the BVH over the faces, using binned SAH.
+func (m *TriangleMesh) build() {
+	n := len(m.faces)
+	m.nodes = m.nodes[:0]
+	m.tris = make([]int, 0, n)
+	if n == 0 {
+		return
+	}
+	mins := make([]Point3, n)
+	maxs := make([]Point3, n)
+	centers := make([]Point3, n)
+	idx := make([]int, n)
+	for i, f := range m.faces {
+		a, b, c := m.vertices[f[0]], m.vertices[f[1]], m.vertices[f[2]]
+		mins[i] = minPoint(minPoint(a, b), c)
+		maxs[i] = maxPoint(maxPoint(a, b), c)
+		for k := X; k <= Z; k++ {
+			centers[i][k] = (mins[i][k] + maxs[i][k]) / 2
+		}
+		idx[i] = i
+	}
+	m.nodes = append(m.nodes, meshNode{})
+	m.buildNode(0, idx, mins, maxs, centers)
+}
+
+func (m *TriangleMesh) buildNode(ni int, idx []int, mins, maxs, centers []Point3) {
+	min, max := mins[idx[0]], maxs[idx[0]]
+	cmin, cmax := centers[idx[0]], centers[idx[0]]
+	for _, i := range idx[1:] {
+		min, max = minPoint(min, mins[i]), maxPoint(max, maxs[i])
+		cmin, cmax = minPoint(cmin, centers[i]), maxPoint(cmax, centers[i])
+	}
+	// pad flat boxes, so axis aligned faces are not missed
+	for k := X; k <= Z; k++ {
+		min[k] -= Epsilon
+		max[k] += Epsilon
+	}
+	m.nodes[ni].min, m.nodes[ni].max = min, max
+
+	axis, split := -1, 0
+	if len(idx) > meshLeafSize {
+		axis, split = bestBinSplit(idx, mins, maxs, centers, cmin, cmax)
+	}
+	if axis < 0 {
+		m.nodes[ni].first = int32(len(m.tris))
+		m.nodes[ni].count = int32(len(idx))
+		m.tris = append(m.tris, idx...)
+		return
+	}
+
+	// partition the faces around the split bin
+	scale := meshBins / (cmax[axis] - cmin[axis])
+	i, j := 0, len(idx)-1
+	for i <= j {
+		if binOf(centers[idx[i]][axis], cmin[axis], scale) < split {
+			i++
+		} else {
+			idx[i], idx[j] = idx[j], idx[i]
+			j--
+		}
+	}
+	if i == 0 || i == len(idx) {
+		i = len(idx) / 2
+	}
+	left := int32(len(m.nodes))
+	m.nodes = append(m.nodes, meshNode{}, meshNode{})
+	m.nodes[ni].left = left
+	m.buildNode(int(left), idx[:i], mins, maxs, centers)
+	m.buildNode(int(left)+1, idx[i:], mins, maxs, centers)
+}
+
+func binOf(c, cmin, scale float64) int {
+	b := int((c - cmin) * scale)
+	if b >= meshBins {
+		b = meshBins - 1
+	}
+	return b
+}
+
+// bestBinSplit returns the axis and bin of the cheapest SAH split, with
+// the faces of bins lower than split on the left. axis is -1 if not
+// splitting is cheaper.
+func bestBinSplit(idx []int, mins, maxs, centers []Point3, cmin, cmax Point3) (int, int) {
+	type bin struct {
+		min, max Point3
+		count    int
+	}
+	bestCost := math.Inf(1)
+	bestAxis, bestSplit := -1, 0
+	var leftArea [meshBins]float64
+	var leftCount [meshBins]int
+	for a := X; a <= Z; a++ {
+		if cmax[a]-cmin[a] < Epsilon {
+			continue
+		}
+		scale := meshBins / (cmax[a] - cmin[a])
+		var bins [meshBins]bin
+		for _, i := range idx {
+			b := &bins[binOf(centers[i][a], cmin[a], scale)]
+			if b.count == 0 {
+				b.min, b.max = mins[i], maxs[i]
+			} else {
+				b.min, b.max = minPoint(b.min, mins[i]), maxPoint(b.max, maxs[i])
+			}
+			b.count++
+		}
+		// sweep from the left: leftArea[s] is the area of bins [0,s)
+		var min, max Point3
+		count := 0
+		for s := 1; s < meshBins; s++ {
+			b := bins[s-1]
+			if b.count > 0 {
+				if count == 0 {
+					min, max = b.min, b.max
+				} else {
+					min, max = minPoint(min, b.min), maxPoint(max, b.max)
+				}
+				count += b.count
+			}
+			leftCount[s] = count
+			if count > 0 {
+				leftArea[s] = area(min, max)
+			}
+		}
+		// sweep from the right
+		count = 0
+		for s := meshBins - 1; s > 0; s-- {
+			b := bins[s]
+			if b.count > 0 {
+				if count == 0 {
+					min, max = b.min, b.max
+				} else {
+					min, max = minPoint(min, b.min), maxPoint(max, b.max)
+				}
+				count += b.count
+			}
+			if count == 0 || leftCount[s] == 0 {
+				continue
+			}
+			cost := leftArea[s]*float64(leftCount[s]) + area(min, max)*float64(count)
+			if cost < bestCost {
+				bestCost, bestAxis, bestSplit = cost, a, s
+			}
+		}
+	}
+	if bestAxis < 0 {
+		return -1, 0
+	}
+	// compare with the cost of a leaf
+	pmin, pmax := mins[idx[0]], maxs[idx[0]]
+	for _, i := range idx[1:] {
+		pmin, pmax = minPoint(pmin, mins[i]), maxPoint(pmax, maxs[i])
+	}
+	if len(idx) <= 2*meshLeafSize && bestCost >= area(pmin, pmax)*float64(len(idx)) {
+		return -1, 0
+	}
+	return bestAxis, bestSplit
+}
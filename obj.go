@@ -0,0 +1,404 @@
+package ray
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoadOBJ loads a Wavefront OBJ file, and its MTL material libraries.
+// See ReadOBJ.
+func LoadOBJ(name string) (*BoundingBox, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	bb, err := ReadOBJ(f, filepath.Dir(name))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return bb, nil
+}
+
+// ReadOBJ reads a Wavefront OBJ model, with material libraries and
+// textures relative to dir. Polygons are triangulated as fans.
+// It returns a BoundingBox holding a TriangleMesh for each object or group
+// (o and g statements), and each material used in it, named after the
+// group (and material if the group has several).
+//
+// Material Kd maps to Surface.Color, Ks to Ks, Ns to Nphong, d or Tr
+// to the transparency Kt, Ni to Ior, map_Kd to the color texture
+// and bump or map_Bump to the bump map.
+func ReadOBJ(r io.Reader, dir string) (*BoundingBox, error) {
+	o := &objReader{
+		dir:       dir,
+		materials: make(map[string]*Surface),
+		textures:  make(map[string]*ImageTexture),
+	}
+	if err := o.read(r); err != nil {
+		return nil, err
+	}
+	return o.result()
+}
+
+// objCorner is a face corner: indices of position, texture coords and normal,
+// -1 if missing.
+type objCorner [3]int
+
+// objMesh gathers the faces of a group using the same material.
+type objMesh struct {
+	group    string
+	material string
+	faces    [][3]objCorner
+}
+
+type objReader struct {
+	dir       string
+	positions []Point3
+	uvs       [][2]float64
+	normals   []Vector3
+	meshes    []*objMesh
+	cur       *objMesh
+	group     string
+	material  string
+	materials map[string]*Surface
+	textures  map[string]*ImageTexture
+}
+
+func (o *objReader) read(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if err := o.statement(fields); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return sc.Err()
+}
+
+func (o *objReader) statement(fields []string) error {
+	args := fields[1:]
+	switch fields[0] {
+	case "v":
+		p, err := parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		o.positions = append(o.positions, Point3{p[0], p[1], p[2]})
+	case "vt":
+		p, err := parseFloats(args, 1)
+		if err != nil {
+			return err
+		}
+		uv := [2]float64{p[0], 0}
+		if len(p) > 1 {
+			uv[1] = p[1]
+		}
+		o.uvs = append(o.uvs, uv)
+	case "vn":
+		p, err := parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		o.normals = append(o.normals, Vector3{p[0], p[1], p[2]})
+	case "f":
+		return o.face(args)
+	case "o", "g":
+		o.group = strings.Join(args, " ")
+		o.cur = nil
+	case "usemtl":
+		o.material = strings.Join(args, " ")
+		o.cur = nil
+	case "mtllib":
+		for _, name := range args {
+			if err := o.loadMTL(name); err != nil {
+				// models are often shared without their materials
+				log.Printf("obj: %s", err)
+			}
+		}
+	}
+	// other statements (s, l, p, ...) are ignored
+	return nil
+}
+
+// parseFloats parses at least min floats
+func parseFloats(args []string, min int) ([]float64, error) {
+	if len(args) < min {
+		return nil, fmt.Errorf("%d values expected", min)
+	}
+	f := make([]float64, len(args))
+	for i, a := range args {
+		var err error
+		if f[i], err = strconv.ParseFloat(a, 64); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (o *objReader) face(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("face of %d vertices", len(args))
+	}
+	corners := make([]objCorner, len(args))
+	for i, a := range args {
+		c, err := o.corner(a)
+		if err != nil {
+			return err
+		}
+		corners[i] = c
+	}
+	if o.cur == nil {
+		o.cur = &objMesh{group: o.group, material: o.material}
+		o.meshes = append(o.meshes, o.cur)
+	}
+	for i := 1; i+1 < len(corners); i++ {
+		o.cur.faces = append(o.cur.faces, [3]objCorner{corners[0], corners[i], corners[i+1]})
+	}
+	return nil
+}
+
+// corner parses v, v/vt, v//vn or v/vt/vn
+func (o *objReader) corner(s string) (objCorner, error) {
+	c := objCorner{-1, -1, -1}
+	lens := [3]int{len(o.positions), len(o.uvs), len(o.normals)}
+	for i, p := range strings.SplitN(s, "/", 3) {
+		if p == "" {
+			if i == 0 {
+				return c, fmt.Errorf("bad face vertex %q", s)
+			}
+			continue
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return c, err
+		}
+		// indices start at 1, negative ones are relative to the end
+		if n < 0 {
+			n += lens[i]
+		} else {
+			n--
+		}
+		if n < 0 || n >= lens[i] {
+			return c, fmt.Errorf("face vertex %q: index out of range", s)
+		}
+		c[i] = n
+	}
+	return c, nil
+}
+
+func (o *objReader) result() (*BoundingBox, error) {
+	bb := NewBoundingBox()
+	// groups having several materials get the material in their names
+	materials := make(map[string]int)
+	for _, om := range o.meshes {
+		materials[om.group]++
+	}
+	for _, om := range o.meshes {
+		m, err := o.mesh(om)
+		if err != nil {
+			return nil, err
+		}
+		name := om.group
+		if materials[om.group] > 1 && om.material != "" {
+			name += "/" + om.material
+		}
+		if name != "" {
+			m.SetName(name)
+		}
+		bb.AddObjects(m)
+	}
+	return bb, nil
+}
+
+// mesh builds the TriangleMesh, with a vertex per distinct face corner
+func (o *objReader) mesh(om *objMesh) (*TriangleMesh, error) {
+	index := make(map[objCorner]int)
+	var (
+		vertices []Point3
+		uvs      [][2]float64
+		normals  []Vector3
+		faces    = make([][3]int, len(om.faces))
+	)
+	hasUV, hasNormal := true, true
+	for i, f := range om.faces {
+		for j, c := range f {
+			n, ok := index[c]
+			if !ok {
+				n = len(vertices)
+				index[c] = n
+				vertices = append(vertices, o.positions[c[0]])
+				if c[1] >= 0 {
+					uvs = append(uvs, o.uvs[c[1]])
+				} else {
+					hasUV = false
+					uvs = append(uvs, [2]float64{})
+				}
+				if c[2] >= 0 {
+					normals = append(normals, o.normals[c[2]])
+				} else {
+					hasNormal = false
+					normals = append(normals, Vector3{})
+				}
+			}
+			faces[i][j] = n
+		}
+	}
+	m, err := NewTriangleMesh(vertices, faces)
+	if err != nil {
+		return nil, err
+	}
+	if hasUV {
+		m.SetUVs(uvs)
+	}
+	if hasNormal {
+		m.SetNormals(normals)
+	}
+	if om.material != "" {
+		s, ok := o.materials[om.material]
+		if !ok {
+			log.Printf("obj: unknown material %q", om.material)
+		} else {
+			m.Surface = *s
+		}
+	}
+	return m, nil
+}
+
+// path returns the name of a file referenced by the model, relative
+// names being in the model directory.
+func (o *objReader) path(name string) string {
+	name = filepath.FromSlash(name)
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(o.dir, name)
+}
+
+// loadMTL reads a material library
+func (o *objReader) loadMTL(name string) error {
+	f, err := os.Open(o.path(name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	var cur *Surface
+	line := 0
+	for sc.Scan() {
+		line++
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if fields[0] == "newmtl" {
+			s := DefaultSurface
+			cur = &s
+			o.materials[strings.Join(fields[1:], " ")] = cur
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if err := o.mtlStatement(cur, fields); err != nil {
+			return fmt.Errorf("%s: line %d: %w", name, line, err)
+		}
+	}
+	return sc.Err()
+}
+
+func (o *objReader) mtlStatement(s *Surface, fields []string) error {
+	args := fields[1:]
+	switch fields[0] {
+	case "Kd":
+		c, err := parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		s.Color = FloatColor{R: c[0], G: c[1], B: c[2]}
+	case "Ks":
+		c, err := parseFloats(args, 3)
+		if err != nil {
+			return err
+		}
+		s.Ks = FloatColor{R: c[0], G: c[1], B: c[2]}.gray()
+	case "Ns":
+		n, err := parseFloats(args, 1)
+		if err != nil {
+			return err
+		}
+		s.Nphong = n[0]
+	case "d", "Tr":
+		d, err := parseFloats(args, 1)
+		if err != nil {
+			return err
+		}
+		if fields[0] == "d" {
+			s.Kt = 1 - d[0]
+		} else {
+			s.Kt = d[0]
+		}
+		if s.Kt > 0 && s.Ior == 0 {
+			s.Ior = 1
+		}
+	case "Ni":
+		n, err := parseFloats(args, 1)
+		if err != nil {
+			return err
+		}
+		s.Ior = n[0]
+	case "map_Kd":
+		t, err := o.texture(args)
+		if err != nil {
+			log.Printf("obj: %s", err)
+			return nil
+		}
+		s.ColorTex = t
+	case "bump", "map_Bump", "map_bump":
+		t, err := o.texture(args)
+		if err != nil {
+			log.Printf("obj: %s", err)
+			return nil
+		}
+		s.Bump = t
+		s.BumpScale = 1
+		for i := 0; i+1 < len(args); i++ {
+			if args[i] == "-bm" {
+				if s.BumpScale, err = strconv.ParseFloat(args[i+1], 64); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// texture loads the image of a map_ statement, the file name being the
+// last argument (options like -bm are before it).
+func (o *objReader) texture(args []string) (*ImageTexture, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no texture file")
+	}
+	name := o.path(args[len(args)-1])
+	if t, ok := o.textures[name]; ok {
+		return t, nil
+	}
+	t, err := LoadImageTexture(name)
+	if err != nil {
+		return nil, err
+	}
+	o.textures[name] = t
+	return t, nil
+}
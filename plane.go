@@ -101,6 +101,10 @@ func (p *Plane) Intersect(r Ray) *Hit {
 	}
 	h.globNorm = p.RayToGlobal(h.locNorm)
 	h.globNorm.Normalize()
+	// planar xz mapping, v grows towards -z
+	h.u = (x + 1) / 2
+	h.v = (1 - z) / 2
+	h.setTangents(&p.Transform, Vector3{2, 0, 0}, Vector3{0, 0, -2})
 	h.Surface = &p.Surface
 	h.globRay = r
 	if p.debug(r) {
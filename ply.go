@@ -0,0 +1,359 @@
+package ray
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoadPLY loads a PLY file, see ReadPLY.
+func LoadPLY(name string) (*TriangleMesh, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := ReadPLY(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return m, nil
+}
+
+// ReadPLY reads an ASCII or binary (little or big endian) PLY model as
+// a TriangleMesh. Vertex x, y, z are required, nx, ny, nz normals,
+// red, green, blue colors and s, t (or u, v) texture coords are used
+// if present. Faces are triangulated as fans, other elements are ignored.
+func ReadPLY(r io.Reader) (*TriangleMesh, error) {
+	br := bufio.NewReader(r)
+	h, err := readPLYHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	var rd plyReader
+	switch h.format {
+	case "ascii":
+		sc := bufio.NewScanner(br)
+		sc.Split(bufio.ScanWords)
+		rd = &plyASCII{sc: sc}
+	case "binary_little_endian":
+		rd = &plyBinary{r: br, order: binary.LittleEndian}
+	case "binary_big_endian":
+		rd = &plyBinary{r: br, order: binary.BigEndian}
+	default:
+		return nil, fmt.Errorf("ply: unknown format %q", h.format)
+	}
+
+	var (
+		vertices []Point3
+		normals  []Vector3
+		colors   []FloatColor
+		uvs      [][2]float64
+		faces    [][3]int
+	)
+	for _, e := range h.elements {
+		switch e.name {
+		case "vertex":
+			p := e.props
+			x, y, z := p.index("x"), p.index("y"), p.index("z")
+			if x < 0 || y < 0 || z < 0 {
+				return nil, fmt.Errorf("ply: vertex without x, y, z")
+			}
+			nx, ny, nz := p.index("nx"), p.index("ny"), p.index("nz")
+			cr, cg, cb := p.index("red"), p.index("green"), p.index("blue")
+			s, t := p.index("s", "u", "texture_u"), p.index("t", "v", "texture_v")
+			vals := make([]float64, len(p))
+			for i := 0; i < e.count; i++ {
+				for j, pr := range p {
+					if pr.list != "" {
+						// skip unknown lists
+						if _, err := readPLYList(rd, pr); err != nil {
+							return nil, err
+						}
+						continue
+					}
+					if vals[j], err = rd.value(pr.typ); err != nil {
+						return nil, fmt.Errorf("ply: vertex %d: %w", i, err)
+					}
+				}
+				vertices = append(vertices, Point3{vals[x], vals[y], vals[z]})
+				if nx >= 0 && ny >= 0 && nz >= 0 {
+					normals = append(normals, Vector3{vals[nx], vals[ny], vals[nz]})
+				}
+				if cr >= 0 && cg >= 0 && cb >= 0 {
+					colors = append(colors, FloatColor{
+						R: plyColor(vals[cr], p[cr].typ),
+						G: plyColor(vals[cg], p[cg].typ),
+						B: plyColor(vals[cb], p[cb].typ),
+					})
+				}
+				if s >= 0 && t >= 0 {
+					uvs = append(uvs, [2]float64{vals[s], vals[t]})
+				}
+			}
+		case "face":
+			vi := e.props.index("vertex_indices", "vertex_index")
+			if vi < 0 || e.props[vi].list == "" {
+				return nil, fmt.Errorf("ply: face without vertex_indices list")
+			}
+			if plyFloat(e.props[vi].typ) {
+				return nil, fmt.Errorf("ply: vertex_indices of type %s, an integer type is needed", e.props[vi].typ)
+			}
+			for i := 0; i < e.count; i++ {
+				for j, pr := range e.props {
+					if pr.list == "" {
+						if _, err := rd.value(pr.typ); err != nil {
+							return nil, err
+						}
+						continue
+					}
+					l, err := readPLYList(rd, pr)
+					if err != nil {
+						return nil, fmt.Errorf("ply: face %d: %w", i, err)
+					}
+					if j != vi {
+						continue
+					}
+					for k := 1; k+1 < len(l); k++ {
+						faces = append(faces, [3]int{int(l[0]), int(l[k]), int(l[k+1])})
+					}
+				}
+			}
+		default:
+			if err := skipPLYElement(rd, e); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	m, err := NewTriangleMesh(vertices, faces)
+	if err != nil {
+		return nil, err
+	}
+	if len(normals) > 0 {
+		if err := m.SetNormals(normals); err != nil {
+			return nil, err
+		}
+	}
+	if len(colors) > 0 {
+		if err := m.SetColors(colors); err != nil {
+			return nil, err
+		}
+	}
+	if len(uvs) > 0 {
+		if err := m.SetUVs(uvs); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+type plyProperty struct {
+	name string
+	typ  string
+	list string // type of the list count, empty if not a list
+}
+
+type plyProperties []plyProperty
+
+// index returns the index of the first property having one of the names, or -1
+func (p plyProperties) index(names ...string) int {
+	for _, n := range names {
+		for i, pr := range p {
+			if pr.name == n {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+type plyElement struct {
+	name  string
+	count int
+	props plyProperties
+}
+
+type plyHeader struct {
+	format   string
+	elements []*plyElement
+}
+
+func readPLYHeader(r *bufio.Reader) (*plyHeader, error) {
+	var h plyHeader
+	line, err := r.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "ply" {
+		return nil, fmt.Errorf("ply: not a PLY file")
+	}
+	var cur *plyElement
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("ply: header: %w", err)
+		}
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		switch f[0] {
+		case "format":
+			if len(f) < 2 {
+				return nil, fmt.Errorf("ply: bad format line")
+			}
+			h.format = f[1]
+		case "element":
+			if len(f) < 3 {
+				return nil, fmt.Errorf("ply: bad element line")
+			}
+			n, err := strconv.Atoi(f[2])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("ply: bad element count %q", f[2])
+			}
+			cur = &plyElement{name: f[1], count: n}
+			h.elements = append(h.elements, cur)
+		case "property":
+			if cur == nil {
+				return nil, fmt.Errorf("ply: property outside of element")
+			}
+			var pr plyProperty
+			switch {
+			case len(f) == 5 && f[1] == "list":
+				pr = plyProperty{name: f[4], typ: f[3], list: f[2]}
+			case len(f) == 3:
+				pr = plyProperty{name: f[2], typ: f[1]}
+			default:
+				return nil, fmt.Errorf("ply: bad property line")
+			}
+			if plySize(pr.typ) == 0 || (pr.list != "" && plySize(pr.list) == 0) {
+				return nil, fmt.Errorf("ply: unknown property type in %q", strings.TrimSpace(line))
+			}
+			cur.props = append(cur.props, pr)
+		case "end_header":
+			return &h, nil
+		}
+		// comment, obj_info are ignored
+	}
+}
+
+// plySize returns the size in bytes of a PLY type, 0 if unknown
+func plySize(typ string) int {
+	switch typ {
+	case "char", "uchar", "int8", "uint8":
+		return 1
+	case "short", "ushort", "int16", "uint16":
+		return 2
+	case "int", "uint", "int32", "uint32", "float", "float32":
+		return 4
+	case "double", "float64":
+		return 8
+	}
+	return 0
+}
+
+// plyFloat returns true if typ is a floating point type
+func plyFloat(typ string) bool {
+	switch typ {
+	case "float", "float32", "double", "float64":
+		return true
+	}
+	return false
+}
+
+// plyColor returns a color component in [0,1], integer types being in [0,255]
+func plyColor(v float64, typ string) float64 {
+	if plyFloat(typ) {
+		return v
+	}
+	return v / 255
+}
+
+type plyReader interface {
+	value(typ string) (float64, error)
+}
+
+// maxPLYList is the max length of a list, far more than the vertices
+// of a face, so that a corrupt file doesn't exhaust the memory.
+const maxPLYList = 1 << 16
+
+func readPLYList(rd plyReader, pr plyProperty) ([]float64, error) {
+	n, err := rd.value(pr.list)
+	if err != nil {
+		return nil, err
+	}
+	if n != math.Trunc(n) || n < 0 || n > maxPLYList {
+		return nil, fmt.Errorf("bad list length %v", n)
+	}
+	l := make([]float64, int(n))
+	for i := range l {
+		if l[i], err = rd.value(pr.typ); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func skipPLYElement(rd plyReader, e *plyElement) error {
+	for i := 0; i < e.count; i++ {
+		for _, pr := range e.props {
+			var err error
+			if pr.list != "" {
+				_, err = readPLYList(rd, pr)
+			} else {
+				_, err = rd.value(pr.typ)
+			}
+			if err != nil {
+				return fmt.Errorf("ply: %s %d: %w", e.name, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+type plyASCII struct {
+	sc *bufio.Scanner
+}
+
+func (p *plyASCII) value(typ string) (float64, error) {
+	if !p.sc.Scan() {
+		if err := p.sc.Err(); err != nil {
+			return 0, err
+		}
+		return 0, io.ErrUnexpectedEOF
+	}
+	return strconv.ParseFloat(p.sc.Text(), 64)
+}
+
+type plyBinary struct {
+	r     io.Reader
+	order binary.ByteOrder
+	buf   [8]byte
+}
+
+func (p *plyBinary) value(typ string) (float64, error) {
+	b := p.buf[:plySize(typ)]
+	if _, err := io.ReadFull(p.r, b); err != nil {
+		return 0, err
+	}
+	switch typ {
+	case "char", "int8":
+		return float64(int8(b[0])), nil
+	case "uchar", "uint8":
+		return float64(b[0]), nil
+	case "short", "int16":
+		return float64(int16(p.order.Uint16(b))), nil
+	case "ushort", "uint16":
+		return float64(p.order.Uint16(b)), nil
+	case "int", "int32":
+		return float64(int32(p.order.Uint32(b))), nil
+	case "uint", "uint32":
+		return float64(p.order.Uint32(b)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(p.order.Uint32(b))), nil
+	}
+	return math.Float64frombits(p.order.Uint64(b)), nil
+}
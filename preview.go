@@ -1,3 +1,7 @@
+//go:build !headless && (cgo || windows)
+// +build !headless
+// +build cgo windows
+
 package ray
 
 import (
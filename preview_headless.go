@@ -0,0 +1,33 @@
+//go:build headless || (!cgo && !windows)
+// +build headless !cgo,!windows
+
+package ray
+
+import "log"
+
+// Preview is the observer of a rendering when built without the preview
+// window (with the headless tag, or without cgo): it only waits for the
+// end of the rendering.
+type Preview struct {
+	done chan struct{}
+}
+
+// newPreview ...
+func newPreview(s *Scene) *Preview {
+	if s.Preview {
+		log.Printf("preview window not available in this build")
+	}
+	return &Preview{done: make(chan struct{})}
+}
+
+func (pv *Preview) waitSetup() {}
+
+func (pv *Preview) drawPixels(b []pixel) {}
+
+func (pv *Preview) endRender() {
+	close(pv.done)
+}
+
+func (pv *Preview) run() {
+	<-pv.done
+}
@@ -0,0 +1,228 @@
+package ray
+
+import (
+	"math"
+)
+
+// Procedural textures are solid textures evaluated at the hit point in
+// object coords. Most of them blend 2 sub textures A and B, which can be
+// UniformTexture colors or other procedural textures.
+
+// UniformTexture is a texture of constant color.
+type UniformTexture FloatColor
+
+// ColorAt implements Texture
+func (t UniformTexture) ColorAt(h *Hit) FloatColor {
+	return FloatColor(t)
+}
+
+// Checker alternates A and B in a 3D checkerboard of cubes of side Size.
+type Checker struct {
+	A, B Texture
+	Size float64
+}
+
+// ColorAt implements Texture
+func (t *Checker) ColorAt(h *Hit) FloatColor {
+	p := h.locNorm.pt
+	// offset to avoid flickering on the faces of canonical objects (at 0, ±1)
+	n := math.Floor((p[X]+BigEpsilon)/t.Size) +
+		math.Floor((p[Y]+BigEpsilon)/t.Size) +
+		math.Floor((p[Z]+BigEpsilon)/t.Size)
+	if math.Mod(n, 2) == 0 {
+		return t.A.ColorAt(h)
+	}
+	return t.B.ColorAt(h)
+}
+
+// Stripes alternates A and B in stripes of given Width along Axis (X, Y
+// or Z, clamped). Without positive Width, it is A.
+type Stripes struct {
+	A, B  Texture
+	Width float64
+	Axis  int
+}
+
+// ColorAt implements Texture
+func (t *Stripes) ColorAt(h *Hit) FloatColor {
+	if !(t.Width > 0) {
+		return t.A.ColorAt(h)
+	}
+	n := math.Floor(h.locNorm.pt[clampIndex(t.Axis, 3)] / t.Width)
+	if math.Mod(n, 2) == 0 {
+		return t.A.ColorAt(h)
+	}
+	return t.B.ColorAt(h)
+}
+
+// Gradient blends linearly from A at coord From to B at coord To along Axis
+// (X, Y or Z, clamped). With From = To, it steps from A to B.
+type Gradient struct {
+	A, B     Texture
+	Axis     int
+	From, To float64
+}
+
+// ColorAt implements Texture
+func (t *Gradient) ColorAt(h *Hit) FloatColor {
+	d := h.locNorm.pt[clampIndex(t.Axis, 3)] - t.From
+	if t.To == t.From {
+		if d < 0 {
+			return t.A.ColorAt(h)
+		}
+		return t.B.ColorAt(h)
+	}
+	return blend(t.A, t.B, h, d/(t.To-t.From))
+}
+
+// Noise blends A and B with Perlin noise of given Scale (size of the features).
+type Noise struct {
+	A, B  Texture
+	Scale float64
+}
+
+// ColorAt implements Texture
+func (t *Noise) ColorAt(h *Hit) FloatColor {
+	p := scalePoint(h.locNorm.pt, 1/t.Scale)
+	return blend(t.A, t.B, h, (Noise3(p)+1)/2)
+}
+
+// Turbulence blends A and B with the turbulence function of given
+// Scale and number of Octaves.
+type Turbulence struct {
+	A, B    Texture
+	Scale   float64
+	Octaves int
+}
+
+// ColorAt implements Texture
+func (t *Turbulence) ColorAt(h *Hit) FloatColor {
+	p := scalePoint(h.locNorm.pt, 1/t.Scale)
+	return blend(t.A, t.B, h, Turbulence3(p, t.Octaves))
+}
+
+// Marble blends A and B in veins along the x-axis, of period Scale,
+// distorted by turbulence of given Amount.
+type Marble struct {
+	A, B    Texture
+	Scale   float64
+	Amount  float64
+	Octaves int
+}
+
+// ColorAt implements Texture
+func (t *Marble) ColorAt(h *Hit) FloatColor {
+	p := scalePoint(h.locNorm.pt, 1/t.Scale)
+	f := math.Sin(2*math.Pi*p[X] + t.Amount*Turbulence3(p, t.Octaves))
+	return blend(t.A, t.B, h, (f+1)/2)
+}
+
+// Wood blends A and B in concentric rings around the y-axis, of
+// given Spacing, distorted by noise of given Amount.
+type Wood struct {
+	A, B    Texture
+	Spacing float64
+	Amount  float64
+}
+
+// ColorAt implements Texture
+func (t *Wood) ColorAt(h *Hit) FloatColor {
+	p := scalePoint(h.locNorm.pt, 1/t.Spacing)
+	r := math.Sqrt(square(p[X])+square(p[Z])) + t.Amount*Noise3(p)
+	return blend(t.A, t.B, h, r-math.Floor(r))
+}
+
+// blend returns the mix of textures a and b, f = 0 being a and 1 being b.
+func blend(a, b Texture, h *Hit, f float64) FloatColor {
+	f = math.Max(0, math.Min(1, f))
+	c := a.ColorAt(h).MulF(1 - f)
+	return c.Add(b.ColorAt(h).MulF(f))
+}
+
+func scalePoint(p Point3, f float64) Point3 {
+	return Point3{p[X] * f, p[Y] * f, p[Z] * f}
+}
+
+// Turbulence3 returns the sum of octaves of absolute Perlin noise at p,
+// each one having half the amplitude and twice the frequency of the previous.
+func Turbulence3(p Point3, octaves int) float64 {
+	t, f := 0.0, 1.0
+	for i := 0; i < octaves; i++ {
+		t += math.Abs(Noise3(scalePoint(p, f))) / f
+		f *= 2
+	}
+	return t
+}
+
+// Noise3 returns the (improved) Perlin noise at p, in [-1,1].
+func Noise3(p Point3) float64 {
+	fx, fy, fz := math.Floor(p[X]), math.Floor(p[Y]), math.Floor(p[Z])
+	x, y, z := int(fx)&255, int(fy)&255, int(fz)&255
+	px, py, pz := p[X]-fx, p[Y]-fy, p[Z]-fz
+	u, v, w := fade(px), fade(py), fade(pz)
+
+	a := perm[x] + y
+	aa, ab := perm[a]+z, perm[a+1]+z
+	b := perm[x+1] + y
+	ba, bb := perm[b]+z, perm[b+1]+z
+
+	return lerp(w,
+		lerp(v,
+			lerp(u, grad(perm[aa], px, py, pz), grad(perm[ba], px-1, py, pz)),
+			lerp(u, grad(perm[ab], px, py-1, pz), grad(perm[bb], px-1, py-1, pz))),
+		lerp(v,
+			lerp(u, grad(perm[aa+1], px, py, pz-1), grad(perm[ba+1], px-1, py, pz-1)),
+			lerp(u, grad(perm[ab+1], px, py-1, pz-1), grad(perm[bb+1], px-1, py-1, pz-1))))
+}
+
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+func grad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+	u := y
+	if h < 8 {
+		u = x
+	}
+	v := z
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
+
+// perm is Ken Perlin's reference permutation, repeated twice
+var perm [512]int
+
+func init() {
+	p := [256]int{151, 160, 137, 91, 90, 15,
+		131, 13, 201, 95, 96, 53, 194, 233, 7, 225, 140, 36, 103, 30, 69, 142, 8, 99, 37, 240, 21, 10, 23,
+		190, 6, 148, 247, 120, 234, 75, 0, 26, 197, 62, 94, 252, 219, 203, 117, 35, 11, 32, 57, 177, 33,
+		88, 237, 149, 56, 87, 174, 20, 125, 136, 171, 168, 68, 175, 74, 165, 71, 134, 139, 48, 27, 166,
+		77, 146, 158, 231, 83, 111, 229, 122, 60, 211, 133, 230, 220, 105, 92, 41, 55, 46, 245, 40, 244,
+		102, 143, 54, 65, 25, 63, 161, 1, 216, 80, 73, 209, 76, 132, 187, 208, 89, 18, 169, 200, 196,
+		135, 130, 116, 188, 159, 86, 164, 100, 109, 198, 173, 186, 3, 64, 52, 217, 226, 250, 124, 123,
+		5, 202, 38, 147, 118, 126, 255, 82, 85, 212, 207, 206, 59, 227, 47, 16, 58, 17, 182, 189, 28, 42,
+		223, 183, 170, 213, 119, 248, 152, 2, 44, 154, 163, 70, 221, 153, 101, 155, 167, 43, 172, 9,
+		129, 22, 39, 253, 19, 98, 108, 110, 79, 113, 224, 232, 178, 185, 112, 104, 218, 246, 97, 228,
+		251, 34, 242, 193, 238, 210, 144, 12, 191, 179, 162, 241, 81, 51, 145, 235, 249, 14, 239, 107,
+		49, 192, 214, 31, 181, 199, 106, 157, 184, 84, 204, 176, 115, 121, 50, 45, 127, 4, 150, 254,
+		138, 236, 205, 93, 222, 114, 67, 29, 24, 72, 243, 141, 128, 195, 78, 66, 215, 61, 156, 180}
+	for i := 0; i < 256; i++ {
+		perm[i] = p[i]
+		perm[i+256] = p[i]
+	}
+}
@@ -0,0 +1,57 @@
+package ray
+
+import "math"
+
+// mix returns a well distributed 64 bits hash of x (splitmix64 finalizer)
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// pointSeed returns a seed for jitter made of the coords of p, so that
+// the same point always gets the same samples, whatever the goroutine.
+func pointSeed(p Point3) uint64 {
+	s := mix(math.Float64bits(p[X]))
+	s = mix(s ^ math.Float64bits(p[Y]))
+	return mix(s ^ math.Float64bits(p[Z]))
+}
+
+// jitter returns 2 pseudo random numbers in [0,1) for the i-th sample
+// of the given seed.
+func jitter(seed uint64, i int) (float64, float64) {
+	h := mix(seed + uint64(i)*0x9e3779b97f4a7c15)
+	// 26 bits each, exactly representable
+	return float64(h>>38) / (1 << 26), float64(h>>12&(1<<26-1)) / (1 << 26)
+}
+
+// stratified returns the i-th of n sample points of the unit square,
+// jittered by u, v in [0,1). The first k*k samples, k being the integer
+// square root of n, are spread over a k by k grid, one per cell, the
+// remaining ones are anywhere.
+func stratified(i, n int, u, v float64) (float64, float64) {
+	k := int(math.Sqrt(float64(n)))
+	if i >= k*k {
+		return u, v
+	}
+	return (float64(i%k) + u) / float64(k), (float64(i/k) + v) / float64(k)
+}
+
+// concentricDisk maps the unit square to the unit disk, keeping the
+// stratification (Shirley's concentric mapping).
+func concentricDisk(u, v float64) (float64, float64) {
+	a, b := 2*u-1, 2*v-1
+	if a == 0 && b == 0 {
+		return 0, 0
+	}
+	var r, phi float64
+	if math.Abs(a) > math.Abs(b) {
+		r, phi = a, math.Pi/4*(b/a)
+	} else {
+		r, phi = b, math.Pi/2-math.Pi/4*(a/b)
+	}
+	return r * math.Cos(phi), r * math.Sin(phi)
+}
@@ -1,6 +1,8 @@
 package ray
 
 import (
+	"context"
+	"image"
 	"image/jpeg"
 	"image/png"
 	"io"
@@ -20,6 +22,7 @@ type Scene struct {
 	cam          *Camera
 	lights       []Light
 	objects      []Object
+	bvh          *bvh
 	Ambiant      FloatColor
 	raysPerDepth []int
 	traceChan    chan []pixel
@@ -27,14 +30,33 @@ type Scene struct {
 	num          int
 	lasty        int
 	Preview      bool
+	Workers      int // number of trace workers, 0 for NumCPU/4
+	// Samples is the number of rays per pixel. With 1 (or 0), a single
+	// ray goes through the pixel center, otherwise they are jittered over
+	// the pixel, and the pixels around are weighted by the PixelFilter.
+	Samples     int
+	PixelFilter PixelFilter // DefaultPixelFilter if nil
+	film        *film       // samples weighted by the filter, if supersampling
+	// AdaptiveThreshold enables adaptive antialiasing, instead of Samples,
+	// if > 0: after a first pass of one ray per pixel, the pixels whose
+	// corners differ by more than the threshold, in any color component,
+	// or hit different surfaces, are subdivided up to AdaptiveDepth times.
+	AdaptiveThreshold float64
+	AdaptiveDepth     int       // DefaultAdaptiveDepth if 0
+	adaptive          *adaptive // state of the adaptive antialiasing
 }
 
 type pixel struct {
-	x int
-	y int
-	w int
-	h int
-	c FloatColor
+	x       int
+	y       int
+	w       int
+	h       int
+	c       FloatColor
+	samples []sample // nil without supersampling
+	// adaptive antialiasing
+	surf   *Surface // surface hit by the ray, nil for background
+	refine bool     // subdivide the pixel
+	depth  int      // subdivisions of the refined pixel
 }
 
 // NewScene instantiates a scene with a Camera
@@ -50,12 +72,18 @@ func NewScene(cam *Camera) *Scene {
 	return s
 }
 
+// Camera returns the scene camera
+func (s *Scene) Camera() *Camera {
+	return s.cam
+}
+
 // AddLights adds the lights to the scene
 func (s *Scene) AddLights(list ...Light) {
 	s.lights = append(s.lights, list...)
 }
 
-// AddObjects adds the objects to the scene
+// AddObjects adds the objects to the scene.
+// A BVH is built over all the objects when rendering starts.
 func (s *Scene) AddObjects(list ...Object) {
 	s.objects = append(s.objects, list...)
 }
@@ -88,11 +116,14 @@ func (pb *pixelBatch) add(p pixel) {
 }
 
 func (pb *pixelBatch) flush() {
+	if len(pb.b) == 0 {
+		return
+	}
 	pb.c <- pb.b
 	pb.b = nil
 }
 
-func (s *Scene) progressiveTracingBatch() {
+func (s *Scene) progressiveTracingBatch(ctx context.Context) {
 	max := s.cam.Width
 	if s.cam.Height > s.cam.Width {
 		max = s.cam.Height
@@ -104,31 +135,85 @@ func (s *Scene) progressiveTracingBatch() {
 	}
 
 	pb := newPixelBatch(s.traceChan, 16)
-	pb.add(pixel{x: 0, y: 0, w: s.cam.Width, h: s.cam.Height})
+	add := func(p pixel) {
+		if s.adaptive != nil {
+			// counted once sent, as a cancelled pass doesn't send them all
+			s.adaptive.pending.Add(1)
+		}
+		pb.add(p)
+	}
+	add(pixel{x: 0, y: 0, w: s.cam.Width, h: s.cam.Height})
 	for mod := pow; mod > 0; mod /= 2 {
+		if ctx.Err() != nil {
+			break
+		}
 		for y := 0; y < s.cam.Height; y += mod {
 			for x := 0; x < s.cam.Width; x += mod {
 				if x%(2*mod) == 0 && y%(2*mod) == 0 {
 					continue
 				}
-				pb.add(pixel{x: x, y: y, w: mod, h: mod})
+				add(pixel{x: x, y: y, w: mod, h: mod})
 			}
 		}
 	}
 	pb.flush()
 }
 
-// Raytrace ...
+// Raytrace renders the scene, showing its progress in a preview window.
+// Use Render to render without window, e.g. on a headless machine.
 func (s *Scene) Raytrace() {
 	s.Preview = true
+	pv := newPreview(s)
+	s.start(context.Background(), pv)
+	pv.run()
+}
+
+// Render traces the scene without any window and returns the finished image.
+// If ctx is cancelled, rendering stops early and ctx.Err() is returned.
+func (s *Scene) Render(ctx context.Context) (*image.RGBA, error) {
+	<-s.start(ctx, nil)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.cam.Image, nil
+}
+
+// observer is notified of the rendering progress (e.g. the preview window).
+type observer interface {
+	waitSetup()
+	drawPixels([]pixel)
+	endRender()
+}
+
+// start launches the trace and draw workers, the returned channel is closed
+// when the image is complete. obs may be nil.
+func (s *Scene) start(ctx context.Context, obs observer) <-chan struct{} {
 	s.traceChan = make(chan []pixel, 1000)
 	s.drawChan = make(chan []pixel, 1000)
+	done := make(chan struct{})
+	// the trace workers must not change the objects
+	for _, o := range s.objects {
+		prepare(o)
+	}
+	s.bvh = newBVH(s.objects)
+	s.film = nil
+	if s.Samples > 1 && s.AdaptiveThreshold <= 0 {
+		f := s.PixelFilter
+		if f == nil {
+			f = DefaultPixelFilter
+		}
+		s.film = newFilm(s.cam.Width, s.cam.Height, f)
+	}
+	s.adaptive = nil
+	if s.AdaptiveThreshold > 0 {
+		s.adaptive = newAdaptive(s.cam.Width, s.cam.Height)
+	}
 
 	var wg sync.WaitGroup
 	// start trace workers
-	for i := 0; i < runtime.NumCPU()/4; i++ {
-		go s.traceWorker(&wg)
+	for i := 0; i < s.workers(); i++ {
 		wg.Add(1)
+		go s.traceWorker(ctx, &wg)
 	}
 
 	/*
@@ -146,46 +231,118 @@ func (s *Scene) Raytrace() {
 			}
 		}()
 	*/
-	pv := newPreview(s)
 	// start draw worker
-	go s.drawWorker(pv)
+	go s.drawWorker(obs, done)
 	// send screen coords to workers
 	go func() {
-		pv.waitSetup()
-		s.progressiveTracingBatch()
+		if obs != nil {
+			obs.waitSetup()
+		}
+		s.progressiveTracingBatch(ctx)
+		if s.adaptive != nil && s.adaptive.wait(ctx) {
+			s.refineTracingBatch(ctx)
+		}
 		close(s.traceChan)
 		wg.Wait()
 		log.Printf("rays per depth: %v", s.raysPerDepth)
 		close(s.drawChan)
 	}()
-	pv.run()
+	return done
+}
+
+// workers returns the number of trace workers to start.
+func (s *Scene) workers() int {
+	if s.Workers > 0 {
+		return s.Workers
+	}
+	n := runtime.NumCPU() / 4
+	if n < 1 {
+		n = 1
+	}
+	return n
 }
 
-func (s *Scene) traceWorker(wg *sync.WaitGroup) {
+func (s *Scene) traceWorker(ctx context.Context, wg *sync.WaitGroup) {
 	pb := newPixelBatch(s.drawChan, 16)
 	for b := range s.traceChan {
+		if ctx.Err() != nil {
+			// cancelled: drain the channel without tracing
+			if s.adaptive != nil {
+				s.adaptive.skip(b)
+			}
+			continue
+		}
 		for _, p := range b {
-			r := s.cam.BuildRay(p.x, p.y)
-			r.x, r.y = p.x, p.y
-			r.Normalize()
-			p.c = s.trace(r, 0)
+			switch {
+			case p.refine:
+				p.c, p.depth = s.refinePixel(p.x, p.y)
+			case s.adaptive != nil:
+				k := s.traceCorner(p.x, p.y, float64(p.x), float64(p.y))
+				p.c, p.surf = k.c, k.surf
+			default:
+				p.c, p.samples = s.tracePixel(p.x, p.y)
+			}
 			pb.add(p)
 		}
+		// don't keep traced pixels waiting for the next batch, which may
+		// never come: the adaptive first pass waits for all its pixels
+		pb.flush()
 	}
-	pb.flush()
 	wg.Done()
 }
 
-func (s *Scene) drawWorker(pv *Preview) {
+// tracePixel returns the color of the pixel x, y, and its samples if
+// supersampling, the color being then their average.
+func (s *Scene) tracePixel(x, y int) (FloatColor, []sample) {
+	if s.Samples <= 1 {
+		// the center, like the average of the samples over the pixel
+		r := s.cam.BuildRayAt(float64(x)+.5, float64(y)+.5)
+		r.x, r.y = x, y
+		r.Normalize()
+		return s.trace(r, 0), nil
+	}
+	samples := make([]sample, s.Samples)
+	seed := mix(uint64(y)<<32 | uint64(x))
+	var c FloatColor
+	for i := range samples {
+		ju, jv := jitter(seed, i)
+		u, v := stratified(i, s.Samples, ju, jv)
+		sm := &samples[i]
+		sm.x, sm.y = float64(x)+u, float64(y)+v
+		r := s.cam.BuildRayAt(sm.x, sm.y)
+		r.x, r.y = x, y
+		r.Normalize()
+		sm.c = s.trace(r, 0)
+		c.Add(sm.c)
+	}
+	return c.MulF(1 / float64(s.Samples)), samples
+}
+
+func (s *Scene) drawWorker(obs observer, done chan struct{}) {
 	for b := range s.drawChan {
 		for _, p := range b {
 			s.num++
-			s.cam.Image.SetRGBA(p.x, p.y, p.c.Color())
 			s.lasty = p.y
+			if s.adaptive != nil {
+				s.adaptive.draw(p)
+			}
+			if p.samples == nil {
+				s.cam.Image.SetRGBA(p.x, p.y, p.c.Color())
+				continue
+			}
+			for _, sm := range p.samples {
+				s.film.add(sm)
+			}
+			s.film.develop(s.cam.Image, p.x, p.y)
+		}
+		if obs != nil {
+			obs.drawPixels(b)
 		}
-		pv.drawPixels(b)
 	}
-	pv.endRender()
+	if obs != nil {
+		obs.endRender()
+	}
+	close(done)
 }
 
 // Background ...
@@ -204,7 +361,19 @@ func (s *Scene) trace(r Ray, depth int) FloatColor {
 	if hit == nil {
 		return s.Background()
 	}
+	return s.shade(r, hit, depth)
+}
+
+// shade returns the color of the hit of the ray r
+func (s *Scene) shade(r Ray, hit *Hit, depth int) FloatColor {
 	//log.Printf("scene: %#v %#v\n", obj, sd)
+	// only accounts for the last ray segment, for texture filtering
+	hit.footprint = r.pt.Dist(hit.globNorm.pt) * s.cam.pixelAngle()
+	if hit.Surface.textured() {
+		sf := hit.Surface.at(hit)
+		hit.Surface = &sf
+	}
+	hit.Surface.perturb(hit)
 	c := s.whitted(r, hit)
 	//log.Printf("--- %d,%d=%v", x, y, c)
 	if hit.Surface.Ks > 0 {
@@ -212,9 +381,47 @@ func (s *Scene) trace(r Ray, depth int) FloatColor {
 		//log.Printf("TRACE --- %d,%d=%v %#v %d", x, y, refl, hit, depth)
 		c = c.Add(refl)
 	}
+	if hit.Surface.Kt > 0 {
+		c = c.Add(s.refraction(hit, depth))
+	}
 	return c
 }
 
+// refraction traces the ray transmitted through the surface, following
+// Snell's law, or reflected in case of total internal reflection.
+func (s *Scene) refraction(h *Hit, depth int) FloatColor {
+	if depth+1 > s.MaxDepth {
+		return FloatColor{}
+	}
+
+	n1, n2 := 1.0, h.Surface.Ior
+	if h.inside {
+		n1, n2 = n2, n1
+	}
+	eta := n1 / n2
+	in := h.globRay.dir
+	in.Normalize()
+	cosNI := -(h.globNorm.dir.Dot(in))
+	k := 1 - eta*eta*(1-cosNI*cosNI)
+	var newRay Ray
+	if k < 0 {
+		// total internal reflection
+		newRay = Ray{
+			pt:  h.globNorm.pt,
+			dir: in.Add(h.globNorm.dir.Mult(2 * cosNI)),
+		}
+	} else {
+		// start just beyond the surface, to avoid hitting it again
+		newRay = Ray{
+			pt:  Point3(Vector3(h.globNorm.pt).Sub(h.geoNorm.Mult(BigEpsilon))),
+			dir: in.Mult(eta).Add(h.globNorm.dir.Mult(eta*cosNI - math.Sqrt(k))),
+		}
+	}
+	newRay.Normalize()
+	rc := s.trace(newRay, depth+1)
+	return rc.MulC(h.Surface.ColorAt(h)).MulF(h.Surface.Kt)
+}
+
 func (s *Scene) reflection(h *Hit, depth int) FloatColor {
 	if depth+1 > s.MaxDepth {
 		return FloatColor{}
@@ -239,66 +446,100 @@ func (s *Scene) whitted(r Ray, h *Hit) FloatColor {
 	//log.Printf("%v", h.Surface.Ka)
 	a = a.MulF(h.Surface.Ka)
 	wc := a
-	for _, li := range s.lights {
-		//log.Printf("norm=%v", h.globNorm)
-		rl := li.RayToLight(h.globNorm.pt)
-		dist := rl.dir.Norm()
-		if dist < Epsilon {
-			if s.debug(r) {
-				//log.Printf("dist < Epsilon")
-			}
+	for k, li := range s.lights {
+		al, ok := li.(AreaLight)
+		if !ok || al.Samples() <= 1 {
+			rl := li.RayToLight(h.globNorm.pt)
+			wc.Add(s.lightSample(r, h, c, li, rl))
 			continue
 		}
-		rl.Normalize()
-		vl := rl.dir
-		cosNL := h.globNorm.dir.Dot(vl)
-		//log.Printf("vl=%v norm=%v cosNL=%f", vl, h.globNorm.dir, cosNL)
-		if cosNL < Epsilon {
-			if s.debug(r) {
-				//log.Printf("cosNL < Epsilon")
-			}
-			continue
-		}
-		//cosNL = 1
-		// shadow?
-		if s.debug(r) {
-			log.Printf("vl=%v norm=%v cosNL=%f", vl, h.globNorm.dir, cosNL)
-			//log.Printf("hidden %v %f", rl, dist)
-		}
-		rl.x, rl.y = r.x, r.y
-		if s.isHidden(rl, dist) {
-			if s.debug(rl) {
-				//log.Printf("hidden")
-			}
-			continue
+		// area light: average the light of the visible samples
+		n := al.Samples()
+		seed := pointSeed(h.globNorm.pt) + uint64(k)
+		var lc FloatColor
+		for i := 0; i < n; i++ {
+			ju, jv := jitter(seed, i)
+			u, v := stratified(i, n, ju, jv)
+			rl := al.RayToSample(h.globNorm.pt, u, v)
+			lc.Add(s.lightSample(r, h, c, li, rl))
 		}
+		wc.Add(lc.MulF(1 / float64(n)))
+	}
+
+	return wc
+}
 
-		// diffuse term
-		fatt := math.Exp(-.01 * dist)
-		if li.Sun() {
-			fatt = 1
+// lightSample returns the diffuse and specular light received by the
+// surface of color c at h, through the ray rl going to a point of li.
+// It is black if the point is hidden.
+func (s *Scene) lightSample(r Ray, h *Hit, c FloatColor, li Light, rl Ray) FloatColor {
+	var wc FloatColor
+	//log.Printf("norm=%v", h.globNorm)
+	dist := rl.dir.Norm()
+	if dist < Epsilon {
+		if s.debug(r) {
+			//log.Printf("dist < Epsilon")
 		}
-		//fatt := 1.0
-		diffuse := li.Color(rl).MulC(c).MulF(h.Surface.Kd).MulF(cosNL).MulF(fatt)
-		_ = diffuse
-		//log.Printf("a=%v diffuse=%v liR=%v cR=%v Kd=%v cos=%v", a, diffuse, li.Color(rl).R, c.R, h.Surface.Kd, cosNL)
-		wc.Add(diffuse)
-
-		// specular term (phong)
-		vr := h.globNorm.dir.Mult(2 * cosNL).Sub(vl)
-		cosRO := vr.Dot(r.dir)
+		return wc
+	}
+	att := lightAttenuation(li)
+	if !isDistant(li) && !att.InRange(dist) {
+		return wc
+	}
+	rl.Normalize()
+	vl := rl.dir
+	cosNL := h.globNorm.dir.Dot(vl)
+	//log.Printf("vl=%v norm=%v cosNL=%f", vl, h.globNorm.dir, cosNL)
+	if cosNL < Epsilon {
 		if s.debug(r) {
-			//log.Printf("vr=%v r=%v cosRO=%f cosNL=%f normale=%v vl=%v", vr, r.dir, cosRO, cosNL, h.globNorm.dir, vl)
+			//log.Printf("cosNL < Epsilon")
 		}
-		if cosRO > 0 {
-			continue
+		return wc
+	}
+	//cosNL = 1
+	lc := li.Color(rl)
+	if lc == (FloatColor{}) {
+		// e.g. outside of a spot light cone
+		return wc
+	}
+	// shadow?
+	if s.debug(r) {
+		log.Printf("vl=%v norm=%v cosNL=%f", vl, h.globNorm.dir, cosNL)
+		//log.Printf("hidden %v %f", rl, dist)
+	}
+	rl.x, rl.y = r.x, r.y
+	hideDist := dist
+	if isDistant(li) {
+		hideDist = math.MaxFloat64
+	}
+	if s.isHidden(rl, hideDist) {
+		if s.debug(rl) {
+			//log.Printf("hidden")
 		}
-		specular := li.Color(rl).MulF(h.Surface.Ks).MulF(math.Pow(cosRO, float64(h.Surface.Nphong))).MulF(fatt)
-		_ = specular
-		//log.Printf("r=%v cosRO=%f pow=%f", r.dir, cosRO, math.Pow(cosRO, float64(h.Surface.Nphong)))
-		wc.Add(specular)
+		return wc
 	}
 
+	// diffuse term
+	fatt := 1.0
+	if !isDistant(li) {
+		fatt = att.Factor(dist)
+	}
+	diffuse := lc.MulC(c).MulF(h.Surface.Kd).MulF(cosNL).MulF(fatt)
+	//log.Printf("a=%v diffuse=%v liR=%v cR=%v Kd=%v cos=%v", a, diffuse, lc.R, c.R, h.Surface.Kd, cosNL)
+	wc.Add(diffuse)
+
+	// specular term (phong)
+	vr := h.globNorm.dir.Mult(2 * cosNL).Sub(vl)
+	cosRO := vr.Dot(r.dir)
+	if s.debug(r) {
+		//log.Printf("vr=%v r=%v cosRO=%f cosNL=%f normale=%v vl=%v", vr, r.dir, cosRO, cosNL, h.globNorm.dir, vl)
+	}
+	if cosRO > 0 {
+		return wc
+	}
+	specular := lc.MulF(h.Surface.Ks).MulF(math.Pow(cosRO, float64(h.Surface.Nphong))).MulF(fatt)
+	//log.Printf("r=%v cosRO=%f pow=%f", r.dir, cosRO, math.Pow(cosRO, float64(h.Surface.Nphong)))
+	wc.Add(specular)
 	return wc
 }
 
@@ -314,14 +555,17 @@ func (*Scene) debug(r Ray) bool {
 
 // does rl intersect an object closer than dist?
 func (s *Scene) isHidden(rl Ray, dist float64) bool {
+	if s.bvh != nil {
+		return s.bvh.hidden(rl, dist)
+	}
 	for _, obj := range s.objects {
 		h := obj.Intersect(rl)
 		if h == nil {
 			continue
 		}
-		v := Vector3{h.globRay.pt[X] - rl.pt[X], h.globRay.pt[Y] - rl.pt[Y], h.globRay.pt[Z] - rl.pt[Z]}
+		v := NewVec(rl.pt, h.globNorm.pt)
 		if s.debug(rl) {
-			log.Printf("isHidden by %s, inter=%v rl=%v v=%v, |v|=%f", obj.Name(), h.globRay.pt, rl.pt, v, v.Norm())
+			log.Printf("isHidden by %s, inter=%v rl=%v v=%v, |v|=%f", obj.Name(), h.globNorm.pt, rl.pt, v, v.Norm())
 		}
 
 		if v.Norm() < dist {
@@ -333,6 +577,9 @@ func (s *Scene) isHidden(rl Ray, dist float64) bool {
 
 // findIntersection finds the closest intersecting object, if any.
 func (s *Scene) findIntersection(r Ray) *Hit {
+	if s.bvh != nil {
+		return s.bvh.intersect(r)
+	}
 	minDist := math.MaxFloat64
 	var h *Hit
 	for _, o := range s.objects {
@@ -351,7 +598,7 @@ func (s *Scene) findIntersection(r Ray) *Hit {
 
 // WriteJPG ...
 func (s *Scene) WriteJPG(name string) error {
-	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0755)
+	f, err := os.Create(name)
 	if err != nil {
 		return err
 	}
@@ -359,16 +606,12 @@ func (s *Scene) WriteJPG(name string) error {
 	return jpeg.Encode(f, s.cam.Image, nil)
 }
 
-// WritePNG ...
+// WritePNG writes the rendered image to the named file, or to stdout
+// if name is empty.
 func (s *Scene) WritePNG(name string) error {
-	if name == "" {
-		if len(os.Args) > 1 {
-			name = os.Args[1]
-		}
-	}
 	var out io.Writer
 	if name != "" {
-		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0755)
+		f, err := os.Create(name)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,1301 @@
+package ray
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SurfacePresets are the surfaces which can be referenced by name
+// in a scene file.
+var SurfacePresets = map[string]*Surface{
+	"default":  &DefaultSurface,
+	"diffuse":  &Diffuse,
+	"ocher2":   &Ocher2,
+	"mirror":   &Mirror,
+	"white1":   &White1,
+	"building": &Building,
+	"glass":    &Glass,
+	"water":    &Water,
+}
+
+// sceneFile is the root of a scene file.
+type sceneFile struct {
+	MaxDepth int              `json:"maxDepth,omitempty"`
+	Samples  int              `json:"samples,omitempty"`
+	Filter   *pixelFilterFile `json:"filter,omitempty"`
+	Adaptive *adaptiveFile    `json:"adaptive,omitempty"`
+	Ambiant  *colorFile       `json:"ambiant,omitempty"`
+	Camera   cameraFile       `json:"camera"`
+	Lights   []lightFile      `json:"lights,omitempty"`
+	Library  []objectFile     `json:"library,omitempty"`
+	Objects  []objectFile     `json:"objects,omitempty"`
+}
+
+// colorFile is a RGB color written as [r, g, b].
+type colorFile [3]float64
+
+func (c colorFile) color() FloatColor {
+	return FloatColor{R: c[0], G: c[1], B: c[2]}
+}
+
+func newColorFile(c FloatColor) colorFile {
+	return colorFile{c.R, c.G, c.B}
+}
+
+// transformFile is one transformation step, only one field must be set.
+// Steps are applied in order, like chained calls to Translate, Scale, etc.
+// Angles are in radians.
+type transformFile struct {
+	Translate *Vector3 `json:"translate,omitempty"`
+	Scale     *Vector3 `json:"scale,omitempty"`
+	RotateX   *float64 `json:"rotateX,omitempty"`
+	RotateY   *float64 `json:"rotateY,omitempty"`
+	RotateZ   *float64 `json:"rotateZ,omitempty"`
+	Matrix    *Matrix4 `json:"matrix,omitempty"`
+}
+
+// pixelFilterFile is a PixelFilter, of type box, tent, gaussian or
+// mitchell. The parameters not set are those of the filter of the same
+// name in PixelFilters.
+type pixelFilterFile struct {
+	Type   string   `json:"type"`
+	Radius float64  `json:"radius,omitempty"`
+	Alpha  float64  `json:"alpha,omitempty"` // gaussian
+	B      *float64 `json:"b,omitempty"`     // mitchell
+	C      *float64 `json:"c,omitempty"`     // mitchell
+}
+
+func (ff *pixelFilterFile) pixelFilter() (PixelFilter, error) {
+	f, ok := PixelFilters[ff.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown filter type %q", ff.Type)
+	}
+	if ff.Radius < 0 || ff.Alpha < 0 {
+		return nil, fmt.Errorf("filter: negative radius or alpha")
+	}
+	switch f := f.(type) {
+	case BoxFilter:
+		if ff.Radius > 0 {
+			f.R = ff.Radius
+		}
+		return f, nil
+	case TentFilter:
+		if ff.Radius > 0 {
+			f.R = ff.Radius
+		}
+		return f, nil
+	case GaussianFilter:
+		if ff.Radius > 0 {
+			f.R = ff.Radius
+		}
+		if ff.Alpha > 0 {
+			f.Alpha = ff.Alpha
+		}
+		return f, nil
+	case MitchellFilter:
+		if ff.Radius > 0 {
+			f.R = ff.Radius
+		}
+		if ff.B != nil {
+			f.B = *ff.B
+		}
+		if ff.C != nil {
+			f.C = *ff.C
+		}
+		return f, nil
+	}
+	return f, nil
+}
+
+func newPixelFilterFile(f PixelFilter) (*pixelFilterFile, error) {
+	switch f := f.(type) {
+	case nil:
+		return nil, nil
+	case BoxFilter:
+		return &pixelFilterFile{Type: "box", Radius: f.R}, nil
+	case TentFilter:
+		return &pixelFilterFile{Type: "tent", Radius: f.R}, nil
+	case GaussianFilter:
+		return &pixelFilterFile{Type: "gaussian", Radius: f.R, Alpha: f.Alpha}, nil
+	case MitchellFilter:
+		return &pixelFilterFile{Type: "mitchell", Radius: f.R, B: &f.B, C: &f.C}, nil
+	}
+	return nil, fmt.Errorf("unsupported filter %T", f)
+}
+
+// adaptiveFile enables adaptive antialiasing
+type adaptiveFile struct {
+	Threshold float64 `json:"threshold"`
+	Depth     int     `json:"depth,omitempty"`
+}
+
+type cameraFile struct {
+	Focal      float64         `json:"focal"`
+	Width      float64         `json:"width"`
+	Height     float64         `json:"height"`
+	ImageWidth int             `json:"imageWidth"`
+	Transform  []transformFile `json:"transform,omitempty"`
+}
+
+type lightFile struct {
+	Type      string          `json:"type"`
+	Color     colorFile       `json:"color"`
+	Sun       bool            `json:"sun,omitempty"`
+	Samples   int             `json:"samples,omitempty"` // area lights
+	Inner     float64         `json:"inner,omitempty"`   // spot
+	Outer     float64         `json:"outer,omitempty"`   // spot
+	Transform []transformFile `json:"transform,omitempty"`
+	// default if nil
+	Attenuation *attenuationFile `json:"attenuation,omitempty"`
+}
+
+// attenuationFile is an Attenuation, of model none, inverse-square,
+// polynomial or exponential.
+type attenuationFile struct {
+	Model     string  `json:"model"`
+	Intensity float64 `json:"intensity,omitempty"`
+	Constant  float64 `json:"constant,omitempty"`
+	Linear    float64 `json:"linear,omitempty"`
+	Quadratic float64 `json:"quadratic,omitempty"`
+	Rate      float64 `json:"rate,omitempty"`
+	Range     float64 `json:"range,omitempty"`
+}
+
+var attenuationModels = map[string]AttenuationModel{
+	"none":           NoAttenuation,
+	"inverse-square": InverseSquareAttenuation,
+	"polynomial":     PolynomialAttenuation,
+	"exponential":    ExponentialAttenuation,
+}
+
+func (af *attenuationFile) attenuation() (Attenuation, error) {
+	if af == nil {
+		return DefaultAttenuation, nil
+	}
+	m, ok := attenuationModels[af.Model]
+	if !ok {
+		return Attenuation{}, fmt.Errorf("unknown attenuation model %q", af.Model)
+	}
+	if af.Range < 0 {
+		return Attenuation{}, fmt.Errorf("attenuation: negative range")
+	}
+	switch m {
+	case InverseSquareAttenuation:
+		if af.Intensity <= 0 {
+			return Attenuation{}, fmt.Errorf("inverse-square attenuation: a positive intensity is needed")
+		}
+	case PolynomialAttenuation:
+		if af.Constant < 0 || af.Linear < 0 || af.Quadratic < 0 ||
+			af.Constant+af.Linear+af.Quadratic <= 0 {
+			return Attenuation{}, fmt.Errorf("polynomial attenuation: coefficients >= 0, not all 0, are needed")
+		}
+	}
+	return Attenuation{
+		Model:     m,
+		Intensity: af.Intensity,
+		Constant:  af.Constant,
+		Linear:    af.Linear,
+		Quadratic: af.Quadratic,
+		Rate:      af.Rate,
+		Range:     af.Range,
+	}, nil
+}
+
+func newAttenuationFile(a *Attenuation) *attenuationFile {
+	if *a == DefaultAttenuation {
+		return nil
+	}
+	af := &attenuationFile{Range: a.Range}
+	for name, m := range attenuationModels {
+		if m == a.Model {
+			af.Model = name
+		}
+	}
+	switch a.Model {
+	case InverseSquareAttenuation:
+		af.Intensity = a.Intensity
+	case PolynomialAttenuation:
+		af.Constant, af.Linear, af.Quadratic = a.Constant, a.Linear, a.Quadratic
+	case ExponentialAttenuation:
+		af.Rate = a.Rate
+	}
+	return af
+}
+
+type objectFile struct {
+	Type      string          `json:"type"`
+	Name      string          `json:"name,omitempty"`
+	Surface   *surfaceFile    `json:"surface,omitempty"`
+	Transform []transformFile `json:"transform,omitempty"`
+	Children  []objectFile    `json:"children,omitempty"`
+	// cylinder, cone
+	Open bool    `json:"open,omitempty"`
+	Top  float64 `json:"top,omitempty"` // cone only, in [0, 1)
+	// csg
+	Op string `json:"op,omitempty"`
+	// instance, name of the library object
+	Of string `json:"of,omitempty"`
+	// torus
+	Major float64 `json:"major,omitempty"`
+	Minor float64 `json:"minor,omitempty"`
+	// obj, ply, stl, heightfield
+	File string `json:"file,omitempty"`
+	// heightfield, of a grid of heights, or of noise
+	Grid    *[2]int    `json:"grid,omitempty"`
+	Heights []float64  `json:"heights,omitempty"`
+	Noise   *noiseFile `json:"noise,omitempty"`
+	// triangle
+	Points []Point3 `json:"points,omitempty"`
+	// mesh
+	Vertices []Point3     `json:"vertices,omitempty"`
+	Faces    [][3]int     `json:"faces,omitempty"`
+	Normals  []Vector3    `json:"normals,omitempty"`
+	UVs      [][2]float64 `json:"uvs,omitempty"`
+	Colors   []colorFile  `json:"colors,omitempty"`
+	Smooth   bool         `json:"smooth,omitempty"` // also for ply, stl
+}
+
+// noiseFile are the parameters of a heightfield of fractal noise.
+type noiseFile struct {
+	Scale   float64 `json:"scale"`
+	Octaves int     `json:"octaves"`
+}
+
+// surfaceFile starts from a preset (default if empty), then
+// overrides the fields which are set.
+type surfaceFile struct {
+	Preset string     `json:"preset,omitempty"`
+	Ka     *float64   `json:"ka,omitempty"`
+	Kd     *float64   `json:"kd,omitempty"`
+	Ks     *float64   `json:"ks,omitempty"`
+	Color  *colorFile `json:"color,omitempty"`
+	Nphong *float64   `json:"nphong,omitempty"`
+	Kt     *float64   `json:"kt,omitempty"`
+	Ior    *float64   `json:"ior,omitempty"`
+
+	ColorTex *textureFile `json:"colorTexture,omitempty"`
+	KaTex    *textureFile `json:"kaTexture,omitempty"`
+	KdTex    *textureFile `json:"kdTexture,omitempty"`
+	KsTex    *textureFile `json:"ksTexture,omitempty"`
+
+	Bump      *textureFile `json:"bump,omitempty"`
+	BumpScale *float64     `json:"bumpScale,omitempty"`
+	NormalMap *textureFile `json:"normalMap,omitempty"`
+}
+
+type textureFile struct {
+	Type string `json:"type"`
+	// image
+	File   string      `json:"file,omitempty"`
+	Filter string      `json:"filter,omitempty"`
+	Wrap   string      `json:"wrap,omitempty"`
+	Scale  *[2]float64 `json:"scale,omitempty"`
+	// color
+	Color *colorFile `json:"color,omitempty"`
+	// procedural
+	A       *textureFile `json:"a,omitempty"`
+	B       *textureFile `json:"b,omitempty"`
+	Size    float64      `json:"size,omitempty"`
+	Axis    string       `json:"axis,omitempty"`
+	From    float64      `json:"from,omitempty"`
+	To      float64      `json:"to,omitempty"`
+	Amount  float64      `json:"amount,omitempty"`
+	Octaves int          `json:"octaves,omitempty"`
+}
+
+var axisNames = [...]string{"x", "y", "z"}
+
+func parseAxis(name string) (int, error) {
+	if name == "" {
+		return X, nil
+	}
+	for i, n := range axisNames {
+		if n == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown axis %q", name)
+}
+
+// axisName returns the name of the axis a
+func axisName(a int) (string, error) {
+	if a < X || a > Z {
+		return "", fmt.Errorf("invalid axis %d", a)
+	}
+	return axisNames[a], nil
+}
+
+// sceneLoader keeps the state of a scene file being read
+type sceneLoader struct {
+	dir      string             // relative file names are in this directory
+	textures map[string]Texture // image textures by file name
+	library  map[string]Object  // library objects by name
+}
+
+// path returns the name of a file referenced by the scene
+func (l *sceneLoader) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(l.dir, name)
+}
+
+// ReadSceneFile loads a scene from the named JSON scene file.
+// Texture and model file names are relative to the scene file directory.
+func ReadSceneFile(name string) (*Scene, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	l := &sceneLoader{dir: filepath.Dir(name)}
+	s, err := l.read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return s, nil
+}
+
+// ReadScene loads a scene from a JSON description such as:
+//
+//	{
+//	  "ambiant": [0.5, 0.5, 0.5], "samples": 16, "filter": {"type": "mitchell"},
+//	  "camera": {"focal": 16, "width": 16, "height": 9, "imageWidth": 800,
+//	             "transform": [{"translate": [0, 0, 150]}]},
+//	  "lights": [{"type": "point", "color": [1, 1, 1],
+//	              "transform": [{"translate": [-3, -7, 25]}]}],
+//	  "objects": [
+//	    {"type": "sphere", "name": "red", "surface": {"color": [1, 0, 0]},
+//	     "transform": [{"scale": [10, 10, 10]}, {"rotateZ": 0.78}]},
+//	    {"type": "group", "children": [{"type": "cube"}, {"type": "plane"}]}
+//	  ]
+//	}
+//
+// With "samples" > 1, the rays of each pixel are weighted by the "filter"
+// of type box, tent, gaussian (of falloff "alpha") or mitchell (of
+// parameters "b" and "c"), of optional "radius" in pixels. Instead,
+// "adaptive": {"threshold": 0.1, "depth": 2} enables adaptive
+// antialiasing (see Scene.AdaptiveThreshold).
+//
+// Object types are sphere, plane, cube, cylinder (with "open" to remove
+// the caps), cone (with "top" radius if truncated, and "open"), disk,
+// torus (of "major" and "minor" radii), triangle (of 3 "points"), mesh
+// (of "vertices", "faces" as triplets of vertex indices, and optional
+// per-vertex "normals", "uvs" and "colors", or "smooth" to compute the
+// normals), obj (a Wavefront OBJ "file", see ReadOBJ), ply and stl (a PLY
+// or STL "file", with optional "smooth"), heightfield (of a grayscale
+// image "file", or of a "grid" of nx by nz samples and their "heights" or
+// a "noise" of given "scale" and "octaves"), csg (of "op" union,
+// intersection or difference, and 2 solid "children"), group (a Group of
+// "children"), boundingbox (a BoundingBox of "children") and instance
+// (of the object named by "of" in the "library", with its own transform
+// and optional surface overriding the object's ones). Library objects
+// are only rendered through instances. Light types are point,
+// directional (shining along -y, to be rotated), spot (shining along -y
+// in a cone of "inner" and "outer" half angles), and the area lights
+// rect, disk and sphere, of given number of shadow ray "samples".
+// Lights but directional ones have an optional "attenuation", such as
+// {"model": "inverse-square", "intensity": 100, "range": 50}, other
+// models being none, polynomial ("constant", "linear", "quadratic") and
+// exponential ("rate", the default being 0.01). Surfaces start from a
+// preset of SurfacePresets and override the given fields. Surface
+// colorTexture, kaTexture, kdTexture and ksTexture, bump (a height map
+// scaled by bumpScale, 1 by default) and normalMap are textures such as:
+//
+//	{"type": "image", "file": "earth.jpg", "filter": "mipmap", "wrap": "repeat", "scale": [1, 1]}
+//
+// Image filters are nearest, bilinear (default) and mipmap, wraps are
+// repeat (default), clamp and mirror. Other texture types are color
+// (a constant color), and the procedural textures checker, stripes,
+// gradient, noise, turbulence, marble and wood which blend 2 textures:
+//
+//	{"type": "checker", "size": 0.5,
+//	 "a": {"type": "color", "color": [1, 1, 1]},
+//	 "b": {"type": "marble", "size": 2, "amount": 4, "octaves": 5,
+//	       "a": {"type": "color", "color": [0.2, 0.2, 0.2]},
+//	       "b": {"type": "color", "color": [0.9, 0.9, 0.9]}}}
+//
+// size is the checker cubes side, the stripes width, the wood rings spacing
+// or the noise features size, axis (x, y or z) is used by stripes and
+// gradient (blending between coords from and to), amount is the marble
+// and wood distortion, octaves the number of turbulence octaves.
+func ReadScene(r io.Reader) (*Scene, error) {
+	l := &sceneLoader{}
+	return l.read(r)
+}
+
+func (l *sceneLoader) read(r io.Reader) (*Scene, error) {
+	var sf sceneFile
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&sf); err != nil {
+		return nil, err
+	}
+
+	cf := sf.Camera
+	if cf.Focal <= 0 || cf.Width <= 0 || cf.Height <= 0 || cf.ImageWidth <= 0 {
+		return nil, fmt.Errorf("camera: focal, width, height and imageWidth must be positive")
+	}
+	cam := NewCamera(cf.Focal, cf.Width, cf.Height, cf.ImageWidth)
+	if err := applyTransforms(&cam.Transform, cf.Transform); err != nil {
+		return nil, fmt.Errorf("camera: %w", err)
+	}
+
+	s := NewScene(cam)
+	if sf.MaxDepth > 0 {
+		s.MaxDepth = sf.MaxDepth
+	}
+	if sf.Ambiant != nil {
+		s.Ambiant = sf.Ambiant.color()
+	}
+	if sf.Samples < 0 {
+		return nil, fmt.Errorf("samples must not be negative")
+	}
+	s.Samples = sf.Samples
+	if af := sf.Adaptive; af != nil {
+		if af.Threshold <= 0 || af.Depth < 0 {
+			return nil, fmt.Errorf("adaptive: a positive threshold is needed")
+		}
+		s.AdaptiveThreshold, s.AdaptiveDepth = af.Threshold, af.Depth
+	}
+	if sf.Filter != nil {
+		f, err := sf.Filter.pixelFilter()
+		if err != nil {
+			return nil, err
+		}
+		s.PixelFilter = f
+	}
+	for i, lf := range sf.Lights {
+		l, err := lf.light()
+		if err != nil {
+			return nil, fmt.Errorf("light %d: %w", i, err)
+		}
+		s.AddLights(l)
+	}
+	for i, of := range sf.Library {
+		if of.Name == "" {
+			return nil, fmt.Errorf("library object %d: a name is needed", i)
+		}
+		if _, ok := l.library[of.Name]; ok {
+			return nil, fmt.Errorf("library object %d: duplicate name %q", i, of.Name)
+		}
+		o, err := l.object(&of)
+		if err != nil {
+			return nil, fmt.Errorf("library object %d: %w", i, err)
+		}
+		if l.library == nil {
+			l.library = make(map[string]Object)
+		}
+		l.library[of.Name] = o
+	}
+	for i, of := range sf.Objects {
+		o, err := l.object(&of)
+		if err != nil {
+			return nil, fmt.Errorf("object %d: %w", i, err)
+		}
+		s.AddObjects(o)
+	}
+	return s, nil
+}
+
+func applyTransforms(t *Transform, list []transformFile) error {
+	for i, tf := range list {
+		switch {
+		case tf.Translate != nil:
+			t.Translate(tf.Translate[X], tf.Translate[Y], tf.Translate[Z])
+		case tf.Scale != nil:
+			if isNul(tf.Scale[X]) || isNul(tf.Scale[Y]) || isNul(tf.Scale[Z]) {
+				return fmt.Errorf("transform %d: null scale %v", i, *tf.Scale)
+			}
+			t.Scale(tf.Scale[X], tf.Scale[Y], tf.Scale[Z])
+		case tf.RotateX != nil:
+			t.RotateX(*tf.RotateX)
+		case tf.RotateY != nil:
+			t.RotateY(*tf.RotateY)
+		case tf.RotateZ != nil:
+			t.RotateZ(*tf.RotateZ)
+		case tf.Matrix != nil:
+			if err := t.Apply(*tf.Matrix); err != nil {
+				return fmt.Errorf("transform %d: %w", i, err)
+			}
+		default:
+			return fmt.Errorf("transform %d: empty", i)
+		}
+	}
+	return nil
+}
+
+func (lf *lightFile) light() (Light, error) {
+	att, err := lf.Attenuation.attenuation()
+	if err != nil {
+		return nil, err
+	}
+	switch lf.Type {
+	case "point":
+		l := NewPointLight(lf.Color.color())
+		l.SetSun(lf.Sun)
+		l.Attenuation = att
+		if err := applyTransforms(&l.Transform, lf.Transform); err != nil {
+			return nil, err
+		}
+		return l, nil
+	case "directional":
+		l := NewDirectionalLight(lf.Color.color())
+		if err := applyTransforms(&l.Transform, lf.Transform); err != nil {
+			return nil, err
+		}
+		return l, nil
+	case "spot":
+		if lf.Inner < 0 || lf.Outer <= 0 || lf.Inner > lf.Outer {
+			return nil, fmt.Errorf("spot: 0 <= inner <= outer angles are needed")
+		}
+		l := NewSpotLight(lf.Color.color(), lf.Inner, lf.Outer)
+		l.Attenuation = att
+		if err := applyTransforms(&l.Transform, lf.Transform); err != nil {
+			return nil, err
+		}
+		return l, nil
+	case "rect", "disk", "sphere":
+		var (
+			l Light
+			a *areaLight
+		)
+		switch lf.Type {
+		case "rect":
+			rl := NewRectLight(lf.Color.color())
+			l, a = rl, &rl.areaLight
+		case "disk":
+			dl := NewDiskLight(lf.Color.color())
+			l, a = dl, &dl.areaLight
+		default:
+			sl := NewSphereLight(lf.Color.color())
+			l, a = sl, &sl.areaLight
+		}
+		a.SetSun(lf.Sun)
+		a.Attenuation = att
+		if lf.Samples != 0 {
+			a.SetSamples(lf.Samples)
+		}
+		if err := applyTransforms(&a.Transform, lf.Transform); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+	return nil, fmt.Errorf("unknown light type %q", lf.Type)
+}
+
+func (l *sceneLoader) object(of *objectFile) (Object, error) {
+	var (
+		o    Object
+		t    *Transform
+		surf *Surface
+	)
+	switch of.Type {
+	case "sphere":
+		s := NewSphere()
+		o, t, surf = s, &s.Transform, &s.Surface
+	case "plane":
+		p := NewPlane()
+		o, t, surf = p, &p.Transform, &p.Surface
+	case "cube":
+		c := NewCube()
+		o, t, surf = c, &c.Transform, &c.Surface
+	case "cylinder":
+		c := NewCylinder()
+		c.Open = of.Open
+		o, t, surf = c, &c.Transform, &c.Surface
+	case "cone":
+		if of.Top < 0 || of.Top >= 1 {
+			return nil, fmt.Errorf("cone: top radius must be in [0, 1)")
+		}
+		c := NewCone()
+		c.Top, c.Open = of.Top, of.Open
+		o, t, surf = c, &c.Transform, &c.Surface
+	case "torus":
+		if of.Major <= 0 || of.Minor <= 0 {
+			return nil, fmt.Errorf("torus: major and minor radii are needed")
+		}
+		to := NewTorus(of.Major, of.Minor)
+		o, t, surf = to, &to.Transform, &to.Surface
+	case "disk":
+		d := NewDisk()
+		o, t, surf = d, &d.Transform, &d.Surface
+	case "triangle":
+		if len(of.Points) != 3 {
+			return nil, fmt.Errorf("triangle: 3 points are needed")
+		}
+		tr := NewTriangle(of.Points[0], of.Points[1], of.Points[2])
+		o, t, surf = tr, &tr.Transform, &tr.Surface
+	case "mesh":
+		m, err := NewTriangleMesh(of.Vertices, of.Faces)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.SetNormals(of.Normals); err != nil {
+			return nil, err
+		}
+		if err := m.SetUVs(of.UVs); err != nil {
+			return nil, err
+		}
+		var colors []FloatColor
+		for _, c := range of.Colors {
+			colors = append(colors, c.color())
+		}
+		if err := m.SetColors(colors); err != nil {
+			return nil, err
+		}
+		if of.Smooth && of.Normals == nil {
+			m.SmoothNormals()
+		}
+		o, t, surf = m, &m.Transform, &m.Surface
+	case "obj":
+		bb, err := LoadOBJ(l.path(of.File))
+		if err != nil {
+			return nil, err
+		}
+		o, t = bb, &bb.Transform
+	case "ply", "stl":
+		load := LoadPLY
+		if of.Type == "stl" {
+			load = LoadSTL
+		}
+		m, err := load(l.path(of.File))
+		if err != nil {
+			return nil, err
+		}
+		if of.Smooth && m.Normals() == nil {
+			m.SmoothNormals()
+		}
+		o, t, surf = m, &m.Transform, &m.Surface
+	case "heightfield":
+		hf, err := l.heightfield(of)
+		if err != nil {
+			return nil, err
+		}
+		o, t, surf = hf, &hf.Transform, &hf.Surface
+	case "csg":
+		op, err := parseCSGOp(of.Op)
+		if err != nil {
+			return nil, err
+		}
+		if len(of.Children) != 2 {
+			return nil, fmt.Errorf("csg: 2 children are needed")
+		}
+		var solids [2]Solid
+		for i := range of.Children {
+			c, err := l.object(&of.Children[i])
+			if err != nil {
+				return nil, fmt.Errorf("csg: child %d: %w", i, err)
+			}
+			s, ok := c.(Solid)
+			if !ok {
+				return nil, fmt.Errorf("csg: child %d: %s is not a solid", i, of.Children[i].Type)
+			}
+			solids[i] = s
+		}
+		c, err := NewCSG(op, solids[0], solids[1])
+		if err != nil {
+			return nil, err
+		}
+		o, t = c, &c.Transform
+	case "instance":
+		lo, ok := l.library[of.Of]
+		if !ok {
+			return nil, fmt.Errorf("instance: unknown library object %q", of.Of)
+		}
+		in := NewInstance(lo)
+		if of.Surface != nil {
+			in.Surface = new(Surface)
+			surf = in.Surface
+		}
+		o, t = in, &in.Transform
+	case "boundingbox":
+		bb := NewBoundingBox()
+		for i, cf := range of.Children {
+			c, err := l.object(&cf)
+			if err != nil {
+				return nil, fmt.Errorf("%s: child %d: %w", of.Type, i, err)
+			}
+			bb.AddObjects(c)
+		}
+		o, t = bb, &bb.Transform
+	case "group":
+		g := NewGroup()
+		for i, cf := range of.Children {
+			c, err := l.object(&cf)
+			if err != nil {
+				return nil, fmt.Errorf("%s: child %d: %w", of.Type, i, err)
+			}
+			if err := g.AddObjects(c); err != nil {
+				return nil, err
+			}
+		}
+		o, t = g, &g.Transform
+	default:
+		return nil, fmt.Errorf("unknown object type %q", of.Type)
+	}
+	if of.Name != "" {
+		o.(interface{ SetName(string) }).SetName(of.Name)
+	}
+	if of.Surface != nil {
+		if surf == nil {
+			return nil, fmt.Errorf("%s: has no surface", of.Type)
+		}
+		s, err := l.surface(of.Surface)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", of.Type, err)
+		}
+		*surf = s
+	}
+	if err := applyTransforms(t, of.Transform); err != nil {
+		return nil, fmt.Errorf("%s: %w", of.Type, err)
+	}
+	return o, nil
+}
+
+func (l *sceneLoader) surface(sf *surfaceFile) (Surface, error) {
+	s := DefaultSurface
+	if sf.Preset != "" {
+		p, ok := SurfacePresets[strings.ToLower(sf.Preset)]
+		if !ok {
+			return s, fmt.Errorf("unknown surface preset %q", sf.Preset)
+		}
+		s = *p
+	}
+	if sf.Ka != nil {
+		s.Ka = *sf.Ka
+	}
+	if sf.Kd != nil {
+		s.Kd = *sf.Kd
+	}
+	if sf.Ks != nil {
+		s.Ks = *sf.Ks
+	}
+	if sf.Color != nil {
+		s.Color = sf.Color.color()
+	}
+	if sf.Nphong != nil {
+		s.Nphong = *sf.Nphong
+	}
+	if sf.Kt != nil {
+		s.Kt = *sf.Kt
+	}
+	if sf.Ior != nil {
+		s.Ior = *sf.Ior
+	}
+	if s.Kt > 0 && s.Ior <= 0 {
+		return s, fmt.Errorf("transparent surface needs a positive ior")
+	}
+	if sf.BumpScale != nil {
+		s.BumpScale = *sf.BumpScale
+	} else if sf.Bump != nil && s.BumpScale == 0 {
+		s.BumpScale = 1
+	}
+	for _, t := range []struct {
+		tf  *textureFile
+		tex *Texture
+	}{
+		{sf.ColorTex, &s.ColorTex},
+		{sf.KaTex, &s.KaTex},
+		{sf.KdTex, &s.KdTex},
+		{sf.KsTex, &s.KsTex},
+		{sf.Bump, &s.Bump},
+		{sf.NormalMap, &s.NormalMap},
+	} {
+		if t.tf == nil {
+			continue
+		}
+		tex, err := l.texture(t.tf)
+		if err != nil {
+			return s, err
+		}
+		*t.tex = tex
+	}
+	return s, nil
+}
+
+func (l *sceneLoader) texture(tf *textureFile) (Texture, error) {
+	switch tf.Type {
+	case "image":
+		if tf.File == "" {
+			return nil, fmt.Errorf("image texture: no file")
+		}
+		img, err := l.image(l.path(tf.File))
+		if err != nil {
+			return nil, err
+		}
+		// share the mipmaps of the same file, keeping the name as given
+		// to write it back
+		t := *img
+		t.name = tf.File
+		t.Filter, t.Wrap = FilterBilinear, WrapRepeat
+		if tf.Filter != "" {
+			if t.Filter, err = parseFilter(tf.Filter); err != nil {
+				return nil, err
+			}
+		}
+		if tf.Wrap != "" {
+			if t.Wrap, err = parseWrap(tf.Wrap); err != nil {
+				return nil, err
+			}
+		}
+		if tf.Scale != nil {
+			t.UScale, t.VScale = tf.Scale[0], tf.Scale[1]
+		}
+		return &t, nil
+	case "color":
+		if tf.Color == nil {
+			return nil, fmt.Errorf("color texture: no color")
+		}
+		return UniformTexture(tf.Color.color()), nil
+	case "checker", "stripes", "gradient", "noise", "turbulence", "marble", "wood":
+		// procedural, below
+	default:
+		return nil, fmt.Errorf("unknown texture type %q", tf.Type)
+	}
+
+	// procedural textures blending 2 textures
+	if tf.A == nil || tf.B == nil {
+		return nil, fmt.Errorf("%s texture: a and b are needed", tf.Type)
+	}
+	a, err := l.texture(tf.A)
+	if err != nil {
+		return nil, err
+	}
+	b, err := l.texture(tf.B)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Type != "gradient" && tf.Size <= 0 {
+		return nil, fmt.Errorf("%s texture: size must be positive", tf.Type)
+	}
+	axis, err := parseAxis(tf.Axis)
+	if err != nil {
+		return nil, err
+	}
+	switch tf.Type {
+	case "checker":
+		return &Checker{A: a, B: b, Size: tf.Size}, nil
+	case "stripes":
+		return &Stripes{A: a, B: b, Width: tf.Size, Axis: axis}, nil
+	case "gradient":
+		if tf.From == tf.To {
+			return nil, fmt.Errorf("gradient texture: from and to must differ")
+		}
+		return &Gradient{A: a, B: b, Axis: axis, From: tf.From, To: tf.To}, nil
+	case "noise":
+		return &Noise{A: a, B: b, Scale: tf.Size}, nil
+	case "turbulence":
+		return &Turbulence{A: a, B: b, Scale: tf.Size, Octaves: tf.Octaves}, nil
+	case "marble":
+		return &Marble{A: a, B: b, Scale: tf.Size, Amount: tf.Amount, Octaves: tf.Octaves}, nil
+	case "wood":
+		return &Wood{A: a, B: b, Spacing: tf.Size, Amount: tf.Amount}, nil
+	}
+	return nil, fmt.Errorf("unknown texture type %q", tf.Type)
+}
+
+// image loads an image texture once per file name
+func (l *sceneLoader) image(name string) (*ImageTexture, error) {
+	if t, ok := l.textures[name]; ok {
+		return t.(*ImageTexture), nil
+	}
+	t, err := LoadImageTexture(name)
+	if err != nil {
+		return nil, err
+	}
+	if l.textures == nil {
+		l.textures = make(map[string]Texture)
+	}
+	l.textures[name] = t
+	return t, nil
+}
+
+// heightfield creates a heightfield from an image file, noise or heights
+func (l *sceneLoader) heightfield(of *objectFile) (*Heightfield, error) {
+	if of.File != "" {
+		hf, err := LoadHeightfield(l.path(of.File))
+		if err != nil {
+			return nil, err
+		}
+		// as given, to write it back
+		hf.file = of.File
+		return hf, nil
+	}
+	if of.Grid == nil {
+		return nil, fmt.Errorf("heightfield: a file or a grid is needed")
+	}
+	nx, nz := of.Grid[0], of.Grid[1]
+	if of.Noise != nil {
+		return NewNoiseHeightfield(nx, nz, of.Noise.Scale, of.Noise.Octaves)
+	}
+	return NewHeightfield(nx, nz, of.Heights)
+}
+
+// WriteSceneFile saves the scene to the named JSON scene file.
+func (s *Scene) WriteSceneFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := s.WriteScene(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteScene writes the scene as JSON, in the format read by ReadScene.
+// Transforms are written as matrices, and surfaces with all their fields.
+func (s *Scene) WriteScene(w io.Writer) error {
+	sf := sceneFile{
+		MaxDepth: s.MaxDepth,
+		Samples:  s.Samples,
+		Camera: cameraFile{
+			Focal:      s.cam.f,
+			Width:      s.cam.dx,
+			Height:     s.cam.dy,
+			ImageWidth: s.cam.Width,
+			Transform:  newTransformFile(&s.cam.Transform),
+		},
+	}
+	amb := newColorFile(s.Ambiant)
+	sf.Ambiant = &amb
+	ff, err := newPixelFilterFile(s.PixelFilter)
+	if err != nil {
+		return err
+	}
+	sf.Filter = ff
+	if s.AdaptiveThreshold > 0 {
+		sf.Adaptive = &adaptiveFile{Threshold: s.AdaptiveThreshold, Depth: s.AdaptiveDepth}
+	}
+	for i, l := range s.lights {
+		lf, err := newLightFile(l)
+		if err != nil {
+			return fmt.Errorf("light %d: %w", i, err)
+		}
+		sf.Lights = append(sf.Lights, lf)
+	}
+	var sw sceneWriter
+	for i, o := range s.objects {
+		of, err := sw.object(o)
+		if err != nil {
+			return fmt.Errorf("object %d: %w", i, err)
+		}
+		sf.Objects = append(sf.Objects, of)
+	}
+	sf.Library = sw.library
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&sf)
+}
+
+func newTransformFile(t *Transform) []transformFile {
+	m := t.Matrix()
+	if m == ID() {
+		return nil
+	}
+	return []transformFile{{Matrix: &m}}
+}
+
+func newLightFile(l Light) (lightFile, error) {
+	switch l := l.(type) {
+	case *PointLight:
+		return lightFile{
+			Type:        "point",
+			Color:       newColorFile(l.c),
+			Sun:         l.sun,
+			Transform:   newTransformFile(&l.Transform),
+			Attenuation: newAttenuationFile(&l.Attenuation),
+		}, nil
+	case *DirectionalLight:
+		return lightFile{
+			Type:      "directional",
+			Color:     newColorFile(l.c),
+			Transform: newTransformFile(&l.Transform),
+		}, nil
+	case *SpotLight:
+		return lightFile{
+			Type:        "spot",
+			Color:       newColorFile(l.c),
+			Inner:       l.Inner,
+			Outer:       l.Outer,
+			Transform:   newTransformFile(&l.Transform),
+			Attenuation: newAttenuationFile(&l.Attenuation),
+		}, nil
+	case *RectLight:
+		return newAreaLightFile("rect", &l.areaLight), nil
+	case *DiskLight:
+		return newAreaLightFile("disk", &l.areaLight), nil
+	case *SphereLight:
+		return newAreaLightFile("sphere", &l.areaLight), nil
+	}
+	return lightFile{}, fmt.Errorf("unsupported light %T", l)
+}
+
+func newAreaLightFile(typ string, a *areaLight) lightFile {
+	return lightFile{
+		Type:        typ,
+		Color:       newColorFile(a.c),
+		Sun:         a.sun,
+		Samples:     a.samples,
+		Transform:   newTransformFile(&a.Transform),
+		Attenuation: newAttenuationFile(&a.Attenuation),
+	}
+}
+
+func newSurfaceFile(s *Surface) (*surfaceFile, error) {
+	c := newColorFile(s.Color)
+	sf := &surfaceFile{
+		Ka:     &s.Ka,
+		Kd:     &s.Kd,
+		Ks:     &s.Ks,
+		Color:  &c,
+		Nphong: &s.Nphong,
+	}
+	if s.Kt > 0 {
+		sf.Kt, sf.Ior = &s.Kt, &s.Ior
+	}
+	if s.Bump != nil {
+		sf.BumpScale = &s.BumpScale
+	}
+	for _, t := range []struct {
+		tex Texture
+		tf  **textureFile
+	}{
+		{s.ColorTex, &sf.ColorTex},
+		{s.KaTex, &sf.KaTex},
+		{s.KdTex, &sf.KdTex},
+		{s.KsTex, &sf.KsTex},
+		{s.Bump, &sf.Bump},
+		{s.NormalMap, &sf.NormalMap},
+	} {
+		if t.tex == nil {
+			continue
+		}
+		tf, err := newTextureFile(t.tex)
+		if err != nil {
+			return nil, err
+		}
+		*t.tf = tf
+	}
+	return sf, nil
+}
+
+func newTextureFile(t Texture) (*textureFile, error) {
+	switch t := t.(type) {
+	case *ImageTexture:
+		if t.name == "" {
+			return nil, fmt.Errorf("image texture not loaded from a file")
+		}
+		return &textureFile{
+			Type:   "image",
+			File:   t.name,
+			Filter: t.Filter.String(),
+			Wrap:   t.Wrap.String(),
+			Scale:  &[2]float64{t.UScale, t.VScale},
+		}, nil
+	case UniformTexture:
+		c := newColorFile(FloatColor(t))
+		return &textureFile{Type: "color", Color: &c}, nil
+	case *Checker:
+		return newBlendFile("checker", t.A, t.B, textureFile{Size: t.Size})
+	case *Stripes:
+		axis, err := axisName(t.Axis)
+		if err != nil {
+			return nil, fmt.Errorf("stripes texture: %w", err)
+		}
+		return newBlendFile("stripes", t.A, t.B, textureFile{Size: t.Width, Axis: axis})
+	case *Gradient:
+		axis, err := axisName(t.Axis)
+		if err != nil {
+			return nil, fmt.Errorf("gradient texture: %w", err)
+		}
+		return newBlendFile("gradient", t.A, t.B, textureFile{Axis: axis, From: t.From, To: t.To})
+	case *Noise:
+		return newBlendFile("noise", t.A, t.B, textureFile{Size: t.Scale})
+	case *Turbulence:
+		return newBlendFile("turbulence", t.A, t.B, textureFile{Size: t.Scale, Octaves: t.Octaves})
+	case *Marble:
+		return newBlendFile("marble", t.A, t.B, textureFile{Size: t.Scale, Amount: t.Amount, Octaves: t.Octaves})
+	case *Wood:
+		return newBlendFile("wood", t.A, t.B, textureFile{Size: t.Spacing, Amount: t.Amount})
+	}
+	return nil, fmt.Errorf("unsupported texture %T", t)
+}
+
+// newBlendFile completes tf for a procedural texture blending a and b
+func newBlendFile(typ string, a, b Texture, tf textureFile) (*textureFile, error) {
+	var err error
+	tf.Type = typ
+	if tf.A, err = newTextureFile(a); err != nil {
+		return nil, err
+	}
+	if tf.B, err = newTextureFile(b); err != nil {
+		return nil, err
+	}
+	return &tf, nil
+}
+
+// objectName returns the name given to SetName, without the type prefix.
+func objectName(o Object) string {
+	n := o.Name()
+	if i := strings.Index(n, ":"); i >= 0 {
+		return n[i+1:]
+	}
+	return n
+}
+
+// sceneWriter keeps the state of a scene being written
+type sceneWriter struct {
+	library []objectFile      // objects referenced by instances
+	names   map[Object]string // names of the library objects
+	used    map[string]bool   // names already used in the library
+}
+
+// libraryName returns the name of o in the library, writing it there
+// the first time o is referenced by an instance.
+func (sw *sceneWriter) libraryName(o Object) (string, error) {
+	if name, ok := sw.names[o]; ok {
+		return name, nil
+	}
+	if sw.names == nil {
+		sw.names = make(map[Object]string)
+		sw.used = make(map[string]bool)
+	}
+	of, err := sw.object(o)
+	if err != nil {
+		return "", err
+	}
+	name := of.Name
+	for i := len(sw.library); name == "" || sw.used[name]; i++ {
+		name = fmt.Sprintf("object%d", i)
+	}
+	of.Name = name
+	sw.names[o] = name
+	sw.used[name] = true
+	sw.library = append(sw.library, of)
+	return name, nil
+}
+
+func (sw *sceneWriter) object(o Object) (objectFile, error) {
+	of := objectFile{Name: objectName(o)}
+	var surf *Surface
+	switch o := o.(type) {
+	case *Sphere:
+		of.Type = "sphere"
+		surf = &o.Surface
+		of.Transform = newTransformFile(&o.Transform)
+	case *Plane:
+		of.Type = "plane"
+		surf = &o.Surface
+		of.Transform = newTransformFile(&o.Transform)
+	case *Cube:
+		of.Type = "cube"
+		surf = &o.Surface
+		of.Transform = newTransformFile(&o.Transform)
+	case *Cylinder:
+		of.Type = "cylinder"
+		of.Open = o.Open
+		surf = &o.Surface
+		of.Transform = newTransformFile(&o.Transform)
+	case *Cone:
+		of.Type = "cone"
+		of.Top, of.Open = o.Top, o.Open
+		surf = &o.Surface
+		of.Transform = newTransformFile(&o.Transform)
+	case *Torus:
+		of.Type = "torus"
+		of.Major, of.Minor = o.Major, o.Minor
+		surf = &o.Surface
+		of.Transform = newTransformFile(&o.Transform)
+	case *Disk:
+		of.Type = "disk"
+		surf = &o.Surface
+		of.Transform = newTransformFile(&o.Transform)
+	case *Triangle:
+		of.Type = "triangle"
+		a, b, c := o.Points()
+		of.Points = []Point3{a, b, c}
+		surf = &o.Surface
+		of.Transform = newTransformFile(&o.Transform)
+	case *TriangleMesh:
+		of.Type = "mesh"
+		of.Vertices, of.Faces = o.Vertices(), o.Faces()
+		of.Normals, of.UVs = o.Normals(), o.UVs()
+		for _, c := range o.Colors() {
+			of.Colors = append(of.Colors, newColorFile(c))
+		}
+		surf = &o.Surface
+		of.Transform = newTransformFile(&o.Transform)
+	case *Heightfield:
+		of.Type = "heightfield"
+		nx, nz := o.Size()
+		switch {
+		case o.file != "":
+			of.File = o.file
+		case o.noise != nil:
+			of.Grid = &[2]int{nx, nz}
+			of.Noise = &noiseFile{Scale: o.noise.scale, Octaves: o.noise.octaves}
+		default:
+			of.Grid = &[2]int{nx, nz}
+			of.Heights = o.Heights()
+		}
+		surf = &o.Surface
+		of.Transform = newTransformFile(&o.Transform)
+	case *Instance:
+		of.Type = "instance"
+		name, err := sw.libraryName(o.Object)
+		if err != nil {
+			return of, fmt.Errorf("instance: %w", err)
+		}
+		of.Of = name
+		surf = o.Surface
+		of.Transform = newTransformFile(&o.Transform)
+	case *CSG:
+		of.Type = "csg"
+		of.Op = o.Op.String()
+		of.Transform = newTransformFile(&o.Transform)
+		for i, c := range []Solid{o.A, o.B} {
+			cf, err := sw.object(c)
+			if err != nil {
+				return of, fmt.Errorf("csg: child %d: %w", i, err)
+			}
+			of.Children = append(of.Children, cf)
+		}
+	case *Group:
+		of.Type = "group"
+		of.Transform = newTransformFile(&o.Transform)
+		for i, c := range o.Children() {
+			cf, err := sw.object(c)
+			if err != nil {
+				return of, fmt.Errorf("group: child %d: %w", i, err)
+			}
+			of.Children = append(of.Children, cf)
+		}
+	case *BoundingBox:
+		of.Type = "boundingbox"
+		of.Transform = newTransformFile(&o.Transform)
+		for i, c := range o.childs {
+			cf, err := sw.object(c)
+			if err != nil {
+				return of, fmt.Errorf("boundingbox: child %d: %w", i, err)
+			}
+			of.Children = append(of.Children, cf)
+		}
+	default:
+		return of, fmt.Errorf("unsupported object %T", o)
+	}
+	if surf != nil {
+		sf, err := newSurfaceFile(surf)
+		if err != nil {
+			return of, fmt.Errorf("%s: %w", of.Type, err)
+		}
+		of.Surface = sf
+	}
+	return of, nil
+}
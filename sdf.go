@@ -0,0 +1,193 @@
+package ray
+
+import "math"
+
+// SDF is an object defined by a signed distance function in local coords,
+// rendered by sphere tracing within its bounds. Scaling it non-uniformly
+// breaks the distance, unless StepFactor is lowered accordingly.
+type SDF struct {
+	Transform
+	Surface
+	name string
+	Dist DistanceFunc
+	// Precision is the distance to the surface at which it is hit
+	Precision float64
+	// MaxSteps is the max number of marching steps along a ray
+	MaxSteps int
+	// StepFactor is the part of the distance marched at each step,
+	// 1 for exact distance functions, less for approximate ones.
+	StepFactor float64
+	min, max   Point3
+}
+
+// NewSDF creates an object of distance function dist, whose surface is
+// within the bounds min, max.
+func NewSDF(dist DistanceFunc, min, max Point3) *SDF {
+	return &SDF{
+		Transform:  IDTransform,
+		Surface:    DefaultSurface,
+		Dist:       dist,
+		Precision:  1e-4,
+		MaxSteps:   256,
+		StepFactor: 1,
+		min:        min,
+		max:        max,
+	}
+}
+
+// SetName ...
+func (s *SDF) SetName(name string) {
+	s.name = "sdf:" + name
+}
+
+// Name returns the SDF's name
+func (s *SDF) Name() string {
+	return s.name
+}
+
+// Surf ...
+func (s *SDF) Surf() *Surface {
+	return &s.Surface
+}
+
+// Translate applies a translation to the SDF
+func (s *SDF) Translate(x, y, z float64) *SDF {
+	s.Transform.Translate(x, y, z)
+	return s
+}
+
+// RotateX applies a rotation around x-axis to the SDF
+func (s *SDF) RotateX(x float64) *SDF {
+	s.Transform.RotateX(x)
+	return s
+}
+
+// RotateY applies a rotation around y-axis to the SDF
+func (s *SDF) RotateY(y float64) *SDF {
+	s.Transform.RotateY(y)
+	return s
+}
+
+// RotateZ applies a rotation around z-axis to the SDF
+func (s *SDF) RotateZ(z float64) *SDF {
+	s.Transform.RotateZ(z)
+	return s
+}
+
+// Scale applies a scaling transform to the SDF
+func (s *SDF) Scale(x, y, z float64) *SDF {
+	s.Transform.Scale(x, y, z)
+	return s
+}
+
+// Intersect marches along the ray inside the bounds, by steps of the
+// distance to the surface, until it is less than Precision.
+func (s *SDF) Intersect(r Ray) *Hit {
+	locRay := s.RayToLocal(r)
+	dn := locRay.dir.Norm()
+	if dn < Epsilon {
+		return nil
+	}
+	d := locRay.dir.Mult(1 / dn)
+	t0, t1, ok := boxSpan(s.min, s.max, Ray{pt: locRay.pt, dir: d})
+	if !ok || t1 < 0 {
+		return nil
+	}
+	t := math.Max(t0, 0)
+	at := func(t float64) Point3 {
+		return Point3{locRay.pt[X] + t*d[X], locRay.pt[Y] + t*d[Y], locRay.pt[Z] + t*d[Z]}
+	}
+	// rays starting from the surface must leave it before hitting it
+	left := t0 > s.Precision || math.Abs(s.Dist(at(t))) > s.Precision
+	for i := 0; i < s.MaxSteps && t <= t1; i++ {
+		dist := math.Abs(s.Dist(at(t)))
+		switch {
+		case !left:
+			left = dist > 2*s.Precision
+		case dist < s.Precision:
+			if t/dn <= Epsilon {
+				return nil
+			}
+			return s.hit(r, locRay, at(t))
+		}
+		t += math.Max(dist*s.StepFactor, s.Precision)
+	}
+	return nil
+}
+
+// hit returns the hit at local point lp, the normal being the gradient
+// of the distance.
+func (s *SDF) hit(r, locRay Ray, lp Point3) *Hit {
+	var h Hit
+	h.globRay = r
+	h.locRay = locRay
+	h.locNorm.pt = lp
+	h.locNorm.dir = s.gradient(lp)
+	h.locNorm.dir.Normalize()
+	n := h.locNorm.dir
+	// spherical mapping around origin, and a tangent frame of the normal
+	phi := math.Atan2(-lp[Z], lp[X])
+	if phi < 0 {
+		phi += 2 * math.Pi
+	}
+	rad := Vector3(lp).Norm()
+	h.u = phi / (2 * math.Pi)
+	h.v = 0.5
+	if rad > 0 {
+		h.v = 1 - math.Acos(math.Max(-1, math.Min(1, lp[Y]/rad)))/math.Pi
+	}
+	dpdu := Vector3{0, 1, 0}.Cross(n)
+	if dpdu.Norm() < Epsilon {
+		dpdu = Vector3{1, 0, 0}
+	}
+	dpdu.Normalize()
+	h.setTangents(&s.Transform, dpdu, n.Cross(dpdu))
+	// the normal must face the ray, which comes from inside the object
+	if h.locNorm.dir.Dot(locRay.dir) > 0 {
+		h.locNorm.dir.Reverse()
+		h.inside = true
+	}
+	h.globNorm.pt = s.PointToGlobal(lp)
+	h.globNorm.dir = s.NormalToGlobal(h.locNorm.dir)
+	h.globNorm.Normalize()
+	h.Surface = &s.Surface
+	return &h
+}
+
+// gradient returns the gradient of the distance at p, by central
+// differences on a tetrahedron.
+func (s *SDF) gradient(p Point3) Vector3 {
+	e := s.Precision
+	var g Vector3
+	for _, k := range [4]Vector3{{1, -1, -1}, {-1, -1, 1}, {-1, 1, -1}, {1, 1, 1}} {
+		d := s.Dist(Point3{p[X] + e*k[X], p[Y] + e*k[Y], p[Z] + e*k[Z]})
+		g = g.Add(k.Mult(d))
+	}
+	return g
+}
+
+// MinMax returns the bounds given at creation
+func (s *SDF) MinMax() (Point3, Point3) {
+	return s.min, s.max
+}
+
+// boxSpan returns the interval of ray parameters inside the box min, max.
+func boxSpan(min, max Point3, r Ray) (float64, float64, bool) {
+	t0, t1 := math.Inf(-1), math.Inf(1)
+	for a := X; a <= Z; a++ {
+		if r.dir[a] == 0 {
+			if r.pt[a] < min[a] || r.pt[a] > max[a] {
+				return 0, 0, false
+			}
+			continue
+		}
+		ta := (min[a] - r.pt[a]) / r.dir[a]
+		tb := (max[a] - r.pt[a]) / r.dir[a]
+		if ta > tb {
+			ta, tb = tb, ta
+		}
+		t0 = math.Max(t0, ta)
+		t1 = math.Min(t1, tb)
+	}
+	return t0, t1, t0 <= t1
+}
@@ -88,7 +88,29 @@ func (s *Sphere) Intersect(r Ray) *Hit {
 	} else if t < Epsilon {
 		return nil
 	}
+	return s.hit(r, locRay, t)
+}
 
+// Spans implements Solid
+func (s *Sphere) Spans(r Ray) []Span {
+	locRay := s.RayToLocal(r)
+	dir := locRay.dir
+	p := locRay.pt
+	a := square(dir[X]) + square(dir[Y]) + square(dir[Z])
+	b := 2 * (dir[X]*p[X] + dir[Y]*p[Y] + dir[Z]*p[Z])
+	c := square(p[X]) + square(p[Y]) + square(p[Z]) - 1
+	roots := SolveQuadratic(a, b, c)
+	if len(roots) < 2 {
+		return nil
+	}
+	t0, t1 := roots[0], roots[1]
+	return []Span{{T0: t0, T1: t1, H0: s.hit(r, locRay, t0), H1: s.hit(r, locRay, t1)}}
+}
+
+// hit returns the hit at parameter t of the ray
+func (s *Sphere) hit(r, locRay Ray, t float64) *Hit {
+	dir := locRay.dir
+	p := locRay.pt
 	var h Hit
 	//log.Printf("gr=%v lr=%v", r, locRay)
 	h.globRay = r
@@ -134,10 +156,45 @@ func (s *Sphere) Intersect(r Ray) *Hit {
 	if h.globNorm.dir.Dot(w) < 0 {
 		h.globNorm.dir.Reverse()
 	}
+	// the normal must face the ray, which comes from inside the sphere
+	if h.globNorm.dir.Dot(r.dir) > 0 {
+		h.globNorm.dir.Reverse()
+		h.inside = true
+	}
+	s.uv(&h)
 	h.Surface = &s.Surface
 	return &h
 }
 
+// uv sets the spherical mapping of the hit: u is the longitude
+// around the y-axis and v the latitude, 1 at the north pole.
+func (s *Sphere) uv(h *Hit) {
+	lp := h.locNorm.pt
+	phi := math.Atan2(-lp[Z], lp[X])
+	if phi < 0 {
+		phi += 2 * math.Pi
+	}
+	cosTheta := math.Max(-1, math.Min(1, lp[Y]))
+	h.u = phi / (2 * math.Pi)
+	h.v = 1 - math.Acos(cosTheta)/math.Pi
+
+	dpdu := Vector3{2 * math.Pi * lp[Z], 0, -2 * math.Pi * lp[X]}
+	var dpdv Vector3
+	sinTheta := math.Sqrt(1 - cosTheta*cosTheta)
+	if sinTheta < Epsilon {
+		// pole: any tangent frame will do
+		dpdu = Vector3{0, 0, -2 * math.Pi}
+		dpdv = Vector3{-math.Pi * cosTheta, 0, 0}
+	} else {
+		dpdv = Vector3{
+			-math.Pi * lp[Y] * lp[X] / sinTheta,
+			math.Pi * sinTheta,
+			-math.Pi * lp[Y] * lp[Z] / sinTheta,
+		}
+	}
+	h.setTangents(&s.Transform, dpdu, dpdv)
+}
+
 /*
 func debug(r Ray) bool {
 	if r.x == 300 && r.y == 220 {
@@ -0,0 +1,125 @@
+package ray
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoadSTL loads a STL file, see ReadSTL.
+func LoadSTL(name string) (*TriangleMesh, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := ReadSTL(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return m, nil
+}
+
+// ReadSTL reads an ASCII or binary STL model as a TriangleMesh.
+// Vertices at the same position are shared between facets, the
+// mesh is flat shaded (STL facet normals are ignored).
+func ReadSTL(r io.Reader) (*TriangleMesh, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var tris [][3]Point3
+	// binary files start with an 80 bytes header, which can begin
+	// with "solid" too, so rely on the size instead
+	if len(data) >= 84 && len(data) == 84+50*int(binary.LittleEndian.Uint32(data[80:])) {
+		tris = readBinarySTL(data)
+	} else if tris, err = readASCIISTL(data); err != nil {
+		return nil, err
+	}
+
+	index := make(map[Point3]int)
+	var vertices []Point3
+	faces := make([][3]int, len(tris))
+	for i, t := range tris {
+		for j, p := range t {
+			n, ok := index[p]
+			if !ok {
+				n = len(vertices)
+				index[p] = n
+				vertices = append(vertices, p)
+			}
+			faces[i][j] = n
+		}
+	}
+	return NewTriangleMesh(vertices, faces)
+}
+
+func readBinarySTL(data []byte) [][3]Point3 {
+	n := int(binary.LittleEndian.Uint32(data[80:]))
+	tris := make([][3]Point3, n)
+	for i := range tris {
+		// normal (12 bytes), 3 vertices (36 bytes), attributes (2 bytes)
+		f := data[84+50*i+12:]
+		for j := 0; j < 9; j++ {
+			v := math.Float32frombits(binary.LittleEndian.Uint32(f[4*j:]))
+			tris[i][j/3][j%3] = float64(v)
+		}
+	}
+	return tris
+}
+
+func readASCIISTL(data []byte) ([][3]Point3, error) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	var (
+		tris [][3]Point3
+		cur  [3]Point3
+		n    int
+		line int
+	)
+	for sc.Scan() {
+		line++
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "facet":
+			n = 0
+		case "vertex":
+			if n == 3 {
+				return nil, fmt.Errorf("stl: line %d: facet of more than 3 vertices", line)
+			}
+			if len(fields) != 4 {
+				return nil, fmt.Errorf("stl: line %d: 3 coords expected", line)
+			}
+			for i, f := range fields[1:] {
+				var err error
+				if cur[n][i], err = strconv.ParseFloat(f, 64); err != nil {
+					return nil, fmt.Errorf("stl: line %d: %w", line, err)
+				}
+			}
+			n++
+		case "endfacet":
+			if n != 3 {
+				return nil, fmt.Errorf("stl: line %d: facet of %d vertices", line, n)
+			}
+			tris = append(tris, cur)
+		case "solid", "outer", "endloop", "endsolid":
+		default:
+			return nil, fmt.Errorf("stl: line %d: unexpected %q", line, fields[0])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if line == 0 {
+		return nil, fmt.Errorf("stl: empty file")
+	}
+	return tris, nil
+}
@@ -6,7 +6,19 @@ type Surface struct {
 	Ks     float64
 	Color  FloatColor
 	Nphong float64
-	// textures ...
+	Kt     float64 // transparency, 0 for opaque surfaces
+	Ior    float64 // index of refraction, used if Kt > 0
+	// textures, if set, replace the constant Color, Ka, Kd and Ks.
+	// Coefficients use the mean of the texture RGB components.
+	ColorTex Texture
+	KaTex    Texture
+	KdTex    Texture
+	KsTex    Texture
+	// perturbation of the shading normal, by a height map scaled by
+	// BumpScale, or by a tangent space normal map (which takes precedence).
+	Bump      Texture
+	BumpScale float64
+	NormalMap Texture
 }
 
 var DefaultSurface = Surface{
@@ -25,11 +37,42 @@ var Diffuse = Surface{
 	Nphong: 30,
 }
 
+// ColorAt returns the surface color at the hit point: the color texture
+// if any, else the vertex color of the hit if any, else Color.
 func (s *Surface) ColorAt(h *Hit) FloatColor {
 	//log.Printf("s %v", s)
+	if s.ColorTex != nil {
+		return s.ColorTex.ColorAt(h)
+	}
+	if h.color != nil {
+		return *h.color
+	}
 	return s.Color
 }
 
+// textured returns true if any texture is set
+func (s *Surface) textured() bool {
+	return s.ColorTex != nil || s.KaTex != nil || s.KdTex != nil || s.KsTex != nil
+}
+
+// at returns a copy of the surface with the textures evaluated
+// at the hit point into constant Color, Ka, Kd and Ks.
+func (s *Surface) at(h *Hit) Surface {
+	sf := *s
+	sf.Color = s.ColorAt(h)
+	if s.KaTex != nil {
+		sf.Ka = s.KaTex.ColorAt(h).gray()
+	}
+	if s.KdTex != nil {
+		sf.Kd = s.KdTex.ColorAt(h).gray()
+	}
+	if s.KsTex != nil {
+		sf.Ks = s.KsTex.ColorAt(h).gray()
+	}
+	sf.ColorTex, sf.KaTex, sf.KdTex, sf.KsTex = nil, nil, nil, nil
+	return sf
+}
+
 var Ocher2 = Surface{
 	Ka:     0.7,
 	Kd:     0.5,
@@ -61,3 +104,23 @@ var Building = Surface{
 	Color:  FloatColor{R: .5, G: .5, B: .5},
 	Nphong: 10,
 }
+
+var Glass = Surface{
+	Ka:     0,
+	Kd:     0.05,
+	Ks:     0.1,
+	Color:  FloatColor{R: 1, G: 1, B: 1},
+	Nphong: 200,
+	Kt:     0.9,
+	Ior:    1.5,
+}
+
+var Water = Surface{
+	Ka:     0,
+	Kd:     0.1,
+	Ks:     0.2,
+	Color:  FloatColor{R: 0.8, G: 0.9, B: 1},
+	Nphong: 100,
+	Kt:     0.8,
+	Ior:    1.33,
+}
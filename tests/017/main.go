@@ -0,0 +1,24 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/dlecorfec/ray"
+)
+
+// renders tests/017/scene.json, then writes it back to stderr
+func main() {
+	s, err := ray.ReadSceneFile("tests/017/scene.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.Raytrace()
+	err = s.WritePNG("")
+	if err != nil {
+		log.Fatalf(err.Error())
+	}
+	if err := s.WriteScene(os.Stderr); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	"github.com/dlecorfec/ray"
+)
+
+// a noise terrain, partly under water
+func main() {
+	cam := ray.NewCamera(22, 16, 9, 1280)
+	cam.Translate(0, 4, 30)
+	cam.RotateX(-math.Pi / 10).RotateY(math.Pi / 5)
+
+	sun := ray.NewPointLight(ray.White).Translate(-40, 60, 40)
+	sun.SetSun(true)
+
+	terrain, err := ray.NewNoiseHeightfield(257, 257, 0.6, 6)
+	if err != nil {
+		log.Fatal(err)
+	}
+	terrain.Scale(20, 6, 20).Translate(0, -2, 0)
+	terrain.Surface = ray.Diffuse
+	terrain.Surface.Color = ray.FloatColor{R: .45, G: .6, B: .3}
+
+	water := ray.NewPlane().Scale(20, 20, 20).Translate(0, 1, 0)
+	water.Surface = ray.Water
+
+	s := ray.NewScene(cam)
+	s.Ambiant = ray.FloatColor{R: 0.2, G: 0.2, B: 0.2}
+	s.AddLights(sun)
+	s.AddObjects(terrain, water)
+	s.Raytrace()
+	err = s.WritePNG("")
+	if err != nil {
+		log.Fatalf(err.Error())
+	}
+}
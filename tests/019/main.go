@@ -0,0 +1,51 @@
+package main
+
+import (
+	"log"
+	"math"
+	"math/rand"
+
+	"github.com/dlecorfec/ray"
+)
+
+// a forest of 10000 instances of the same tree
+func main() {
+	cam := ray.NewCamera(22, 16, 9, 1280)
+	cam.Translate(0, 6, 60)
+	cam.RotateX(-math.Pi / 12)
+
+	sun := ray.NewPointLight(ray.White).Translate(-40, 80, 60)
+	sun.SetSun(true)
+
+	trunk := ray.NewCylinder().Scale(.15, .5, .15).Translate(0, .5, 0)
+	trunk.Surface = ray.Diffuse
+	trunk.Surface.Color = ray.FloatColor{R: .4, G: .25, B: .1}
+	leaves := ray.NewCone().Scale(.8, 1.5, .8).Translate(0, 2.5, 0)
+	leaves.Surface = ray.Diffuse
+	leaves.Surface.Color = ray.FloatColor{R: .1, G: .45, B: .15}
+	tree := ray.NewBoundingBox()
+	tree.AddObjects(trunk, leaves)
+
+	s := ray.NewScene(cam)
+	s.Ambiant = ray.FloatColor{R: 0.2, G: 0.2, B: 0.2}
+	s.AddLights(sun)
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		for j := 0; j < 100; j++ {
+			size := .7 + .6*rnd.Float64()
+			x := float64(i-50)*2 + rnd.Float64()
+			z := float64(j-100)*2 + rnd.Float64()
+			s.AddObjects(ray.NewInstance(tree).Scale(size, size, size).RotateY(rnd.Float64()*2*math.Pi).Translate(x, 0, z))
+		}
+	}
+
+	ground := ray.NewPlane().Scale(200, 1, 200)
+	ground.Surface = ray.Ocher2
+	s.AddObjects(ground)
+	s.Raytrace()
+	err := s.WritePNG("")
+	if err != nil {
+		log.Fatalf(err.Error())
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	"github.com/dlecorfec/ray"
+)
+
+// soft shadows of a rectangle, a disk and a sphere light
+func main() {
+	cam := ray.NewCamera(22, 16, 9, 1280)
+	cam.Translate(0, 3, 24)
+	cam.RotateX(-math.Pi / 10)
+
+	rect := ray.NewRectLight(ray.FloatColor{R: .5, G: .5, B: .45}).Scale(1.5, 1, .5).Translate(-6, 8, 2)
+	disk := ray.NewDiskLight(ray.FloatColor{R: .3, G: .3, B: .5}).RotateZ(math.Pi/4).Translate(6, 7, 2)
+	disk.SetSamples(25)
+	sphere := ray.NewSphereLight(ray.FloatColor{R: .5, G: .35, B: .25}).Scale(.8, .8, .8).Translate(0, 9, -4)
+
+	ground := ray.NewPlane().Scale(30, 1, 30)
+	ground.Surface = ray.Diffuse
+	s1 := ray.NewSphere().Scale(1.5, 1.5, 1.5).Translate(-3, 1.5, 0)
+	s1.Surface = ray.White1
+	c1 := ray.NewCube().RotateY(math.Pi/5).Translate(3, 1, 0)
+	c1.Surface = ray.White1
+	t1 := ray.NewTorus(1.2, .4).RotateX(math.Pi/2).Translate(0, 1.6, -3)
+	t1.Surface = ray.White1
+
+	s := ray.NewScene(cam)
+	s.Ambiant = ray.FloatColor{R: 0.2, G: 0.2, B: 0.2}
+	s.AddLights(rect, disk, sphere)
+	s.AddObjects(ground, s1, c1, t1)
+	s.Raytrace()
+	err := s.WritePNG("")
+	if err != nil {
+		log.Fatalf(err.Error())
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	"github.com/dlecorfec/ray"
+)
+
+// a dim directional light and 3 colored spot lights, of inverse square
+// attenuation
+func main() {
+	cam := ray.NewCamera(22, 16, 9, 1280)
+	cam.Translate(0, 4, 24)
+	cam.RotateX(-math.Pi / 10)
+
+	sun := ray.NewDirectionalLight(ray.FloatColor{R: .25, G: .25, B: .3}).RotateX(math.Pi / 5).RotateY(-math.Pi / 4)
+	red := ray.NewSpotLight(ray.FloatColor{R: 1, G: .2, B: .2}, .15, .3).RotateZ(.3).Translate(-4, 10, 0)
+	green := ray.NewSpotLight(ray.FloatColor{R: .2, G: 1, B: .2}, .2, .25).Translate(0, 10, -2)
+	blue := ray.NewSpotLight(ray.FloatColor{R: .2, G: .3, B: 1}, 0, .35).RotateZ(-.3).Translate(4, 10, 0)
+	for _, l := range []*ray.SpotLight{red, green, blue} {
+		// physical falloff, about 1 on the ground
+		l.Attenuation = ray.Attenuation{Model: ray.InverseSquareAttenuation, Intensity: 100, Range: 30}
+	}
+
+	ground := ray.NewPlane().Scale(30, 1, 30)
+	ground.Surface = ray.Diffuse
+	s1 := ray.NewSphere().Scale(1.5, 1.5, 1.5).Translate(-1, 1.5, 1)
+	s1.Surface = ray.White1
+	c1 := ray.NewCylinder().Scale(.7, 2, .7).Translate(2.5, 2, -1)
+	c1.Surface = ray.White1
+
+	s := ray.NewScene(cam)
+	s.Ambiant = ray.FloatColor{R: 0.1, G: 0.1, B: 0.1}
+	s.AddLights(sun, red, green, blue)
+	s.AddObjects(ground, s1, c1)
+	s.Raytrace()
+	err := s.WritePNG("")
+	if err != nil {
+		log.Fatalf(err.Error())
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	"github.com/dlecorfec/ray"
+)
+
+// cylinders, cones and disks, capped and open
+func main() {
+	cam := ray.NewCamera(22, 16, 9, 800)
+	cam.Translate(0, 6, 32)
+	cam.RotateX(-math.Pi / 10)
+
+	light := ray.NewPointLight(ray.FloatColor{R: 1, G: 1, B: 1}).Translate(-10, 20, 20)
+
+	ground := ray.NewPlane().Scale(100, 1, 100)
+	ground.Surface = ray.Diffuse
+
+	cyl := ray.NewCylinder().Scale(1, 2, 1).Translate(-6, 2, 0)
+	cyl.Surface = ray.Ocher2
+	tube := ray.NewCylinder().Scale(1, 2, 1).RotateZ(math.Pi/3).Translate(-2, 2, -2)
+	tube.Open = true
+	cone := ray.NewCone().Scale(1.5, 2, 1.5).Translate(2, 2, 0)
+	cone.Surface = ray.Mirror
+	frustum := ray.NewCone().Scale(1.5, 1, 1.5).Translate(6, 1, -1)
+	frustum.Top = .5
+	frustum.Surface = ray.White1
+	disk := ray.NewDisk().Scale(2, 1, 2).RotateX(math.Pi/2).Translate(0, 3, -6)
+	disk.Surface = ray.Ocher2
+
+	s := ray.NewScene(cam)
+	s.Ambiant = ray.FloatColor{R: 0.2, G: 0.2, B: 0.2}
+	s.AddLights(light)
+	s.AddObjects(ground, cyl, tube, cone, frustum, disk)
+	s.Raytrace()
+	err := s.WritePNG("")
+	if err != nil {
+		log.Fatalf(err.Error())
+	}
+}
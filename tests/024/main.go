@@ -0,0 +1,62 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	"github.com/dlecorfec/ray"
+)
+
+// nested groups: a mobile of arms holding spheres, each arm turned in
+// its parent's coords, then edited by name
+func main() {
+	cam := ray.NewCamera(22, 16, 9, 800)
+	cam.Translate(0, 4, 24)
+	cam.RotateX(-math.Pi / 16)
+
+	light := ray.NewPointLight(ray.FloatColor{R: 1, G: 1, B: 1}).Translate(-10, 20, 20)
+
+	ground := ray.NewPlane().Scale(100, 1, 100)
+	ground.Surface = ray.Diffuse
+
+	mobile := ray.NewGroup().Translate(-4, 6, 0)
+	parent := mobile
+	for i := 0; i < 4; i++ {
+		bar := ray.NewCube().Scale(2, .05, .05).Translate(2, 0, 0)
+		bar.Surface = ray.White1
+		ball := ray.NewSphere().Translate(4, -1.2, 0)
+		ball.Surface = ray.Ocher2
+		ball.SetName("ball" + string(rune('0'+i)))
+		arm := ray.NewGroup()
+		arm.SetName("arm" + string(rune('0'+i)))
+		arm.AddObjects(bar, ball)
+		if i > 0 {
+			// hang from the end of the parent arm
+			arm.Scale(.7, .7, .7).RotateY(math.Pi/3).Translate(4, -1.5, 0)
+		}
+		parent.AddObjects(arm)
+		parent = arm
+	}
+	// remove a ball, and move the last one, now a mirror, under the
+	// start of the first arm
+	mobile.Remove("ball2")
+	if first, ok := mobile.Find("arm0").(*ray.Group); ok {
+		if err := mobile.Reparent("ball3", first); err != nil {
+			log.Fatalf(err.Error())
+		}
+	}
+	if ball, ok := mobile.Find("ball3").(*ray.Sphere); ok {
+		ball.Surface = ray.Mirror
+		ball.Translate(-4, -2, 0)
+	}
+
+	s := ray.NewScene(cam)
+	s.Ambiant = ray.FloatColor{R: 0.2, G: 0.2, B: 0.2}
+	s.AddLights(light)
+	s.AddObjects(ground, mobile)
+	s.Raytrace()
+	err := s.WritePNG("")
+	if err != nil {
+		log.Fatalf(err.Error())
+	}
+}
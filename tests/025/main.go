@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	"github.com/dlecorfec/ray"
+)
+
+// supersampling of a checkerboard: 16 jittered rays per pixel, weighted
+// by a Mitchell filter
+func main() {
+	cam := ray.NewCamera(22, 16, 9, 800)
+	cam.Translate(0, 3, 24)
+	cam.RotateX(-math.Pi / 12)
+
+	light := ray.NewPointLight(ray.FloatColor{R: 1, G: 1, B: 1}).Translate(-10, 20, 20)
+
+	ground := ray.NewPlane().Scale(100, 1, 100)
+	ground.Surface = ray.Diffuse
+	ground.ColorTex = &ray.Checker{
+		A:    ray.UniformTexture{R: .9, G: .9, B: .9},
+		B:    ray.UniformTexture{R: .2, G: .2, B: .3},
+		Size: .02,
+	}
+	s1 := ray.NewSphere().Scale(1.5, 1.5, 1.5).Translate(-2, 1.5, 0)
+	s1.Surface = ray.Mirror
+	c1 := ray.NewCube().RotateY(math.Pi/5).Translate(2.5, 1, -1)
+	c1.Surface = ray.Ocher2
+
+	s := ray.NewScene(cam)
+	s.Ambiant = ray.FloatColor{R: 0.2, G: 0.2, B: 0.2}
+	s.Samples = 16
+	s.PixelFilter = ray.MitchellFilter{R: 2, B: 1. / 3, C: 1. / 3}
+	s.AddLights(light)
+	s.AddObjects(ground, s1, c1)
+	s.Raytrace()
+	err := s.WritePNG("")
+	if err != nil {
+		log.Fatalf(err.Error())
+	}
+}
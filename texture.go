@@ -0,0 +1,44 @@
+package ray
+
+// Texture gives a color varying over the surface of an object.
+type Texture interface {
+	// ColorAt returns the texture color at the hit point.
+	ColorAt(h *Hit) FloatColor
+}
+
+// LocalPoint returns the hit point in object coords.
+func (h *Hit) LocalPoint() Point3 {
+	return h.locNorm.pt
+}
+
+// Point returns the hit point in scene coords.
+func (h *Hit) Point() Point3 {
+	return h.globNorm.pt
+}
+
+// Normal returns the normal at the hit point in scene coords.
+func (h *Hit) Normal() Vector3 {
+	return h.globNorm.dir
+}
+
+// UV returns the surface parameterization at the hit point.
+func (h *Hit) UV() (float64, float64) {
+	return h.u, h.v
+}
+
+// Tangents returns the dP/du and dP/dv tangents at the hit point in scene coords.
+func (h *Hit) Tangents() (Vector3, Vector3) {
+	return h.dpdu, h.dpdv
+}
+
+// Footprint returns the approximate size of the rendered pixel on the
+// surface in scene coords, or 0 if unknown.
+func (h *Hit) Footprint() float64 {
+	return h.footprint
+}
+
+// gray returns the mean of the RGB components, used when a texture
+// drives a coefficient.
+func (fc FloatColor) gray() float64 {
+	return (fc.R + fc.G + fc.B) / 3
+}
@@ -0,0 +1,207 @@
+package ray
+
+import "math"
+
+// Torus is a torus around the y-axis, centered on origin: the surface
+// at distance Minor of the circle of radius Major in the xz plane.
+type Torus struct {
+	Transform
+	Surface
+	name  string
+	Major float64
+	Minor float64
+}
+
+// NewTorus creates a torus of given major and minor radii
+func NewTorus(major, minor float64) *Torus {
+	return &Torus{
+		Transform: IDTransform,
+		Surface:   DefaultSurface,
+		Major:     major,
+		Minor:     minor,
+	}
+}
+
+// SetName ...
+func (to *Torus) SetName(name string) {
+	to.name = "torus:" + name
+}
+
+// Name returns the torus's name
+func (to *Torus) Name() string {
+	return to.name
+}
+
+// Surf ...
+func (to *Torus) Surf() *Surface {
+	return &to.Surface
+}
+
+// Translate applies a translation to the torus
+func (to *Torus) Translate(x, y, z float64) *Torus {
+	to.Transform.Translate(x, y, z)
+	return to
+}
+
+// RotateX applies a rotation around x-axis to the torus
+func (to *Torus) RotateX(x float64) *Torus {
+	to.Transform.RotateX(x)
+	return to
+}
+
+// RotateY applies a rotation around y-axis to the torus
+func (to *Torus) RotateY(y float64) *Torus {
+	to.Transform.RotateY(y)
+	return to
+}
+
+// RotateZ applies a rotation around z-axis to the torus
+func (to *Torus) RotateZ(z float64) *Torus {
+	to.Transform.RotateZ(z)
+	return to
+}
+
+// Scale applies a scaling transform to the torus
+func (to *Torus) Scale(x, y, z float64) *Torus {
+	to.Transform.Scale(x, y, z)
+	return to
+}
+
+// Intersect ...
+func (to *Torus) Intersect(r Ray) *Hit {
+	locRay := to.RayToLocal(r)
+	for _, x := range to.crossings(locRay) {
+		if x.t > Epsilon {
+			return to.hit(r, locRay, x.t)
+		}
+	}
+	return nil
+}
+
+// Spans implements Solid
+func (to *Torus) Spans(r Ray) []Span {
+	locRay := to.RayToLocal(r)
+	var spans []Span
+	var cur Span
+	in := false
+	for _, x := range to.crossings(locRay) {
+		switch {
+		case x.enter && !in:
+			cur = Span{T0: x.t, H0: to.hit(r, locRay, x.t)}
+			in = true
+		case !x.enter && in:
+			cur.T1, cur.H1 = x.t, to.hit(r, locRay, x.t)
+			spans = append(spans, cur)
+			in = false
+		}
+	}
+	return spans
+}
+
+// torusCrossing is an intersection of a ray and the torus, entering
+// or leaving it
+type torusCrossing struct {
+	t     float64
+	enter bool
+}
+
+// crossings solves the quartic equation of the torus,
+// (x² + y² + z² + R² - r²)² = 4R²(x² + z²), along the local ray,
+// and returns the intersections in increasing order of t. Tangent ones
+// are ignored.
+func (to *Torus) crossings(locRay Ray) []torusCrossing {
+	dn := locRay.dir.Norm()
+	if dn < Epsilon {
+		return nil
+	}
+	d := locRay.dir.Mult(1 / dn)
+	// start from the bounding sphere to keep the coefficients small
+	R, rr := to.Major, to.Minor
+	v := Vector3(locRay.pt)
+	pd := v.Dot(d)
+	delta := pd*pd - v.Dot(v) + square(R+rr)
+	if delta < 0 {
+		return nil
+	}
+	s0 := -pd - math.Sqrt(delta)
+	p := Point3{v[X] + s0*d[X], v[Y] + s0*d[Y], v[Z] + s0*d[Z]}
+
+	pv := Vector3(p)
+	pd = pv.Dot(d)
+	k := pv.Dot(pv) + R*R - rr*rr
+	r2 := 4 * R * R
+	coefs := []float64{1,
+		4 * pd,
+		4*pd*pd + 2*k - r2*(d[X]*d[X]+d[Z]*d[Z]),
+		4*pd*k - 2*r2*(p[X]*d[X]+p[Z]*d[Z]),
+		k*k - r2*(p[X]*p[X]+p[Z]*p[Z]),
+	}
+	roots := SolveQuartic(coefs[0], coefs[1], coefs[2], coefs[3], coefs[4])
+	xs := make([]torusCrossing, 0, len(roots))
+	for _, x := range roots {
+		// the quartic is negative inside the torus
+		_, df := evalPoly(x, coefs)
+		if df == 0 {
+			continue
+		}
+		// x is along the unit direction, back to the local ray parameter
+		xs = append(xs, torusCrossing{t: (s0 + x) / dn, enter: df < 0})
+	}
+	return xs
+}
+
+// hit returns the hit at parameter t of the local ray
+func (to *Torus) hit(r, locRay Ray, t float64) *Hit {
+	var h Hit
+	h.globRay = r
+	h.locRay = locRay
+	lp := Point3{
+		locRay.pt[X] + t*locRay.dir[X],
+		locRay.pt[Y] + t*locRay.dir[Y],
+		locRay.pt[Z] + t*locRay.dir[Z],
+	}
+	h.locNorm.pt = lp
+	to.normalUV(&h)
+	// the normal must face the ray, which comes from inside the torus
+	if h.locNorm.dir.Dot(locRay.dir) > 0 {
+		h.locNorm.dir.Reverse()
+		h.inside = true
+	}
+	h.globNorm.pt = to.PointToGlobal(lp)
+	h.globNorm.dir = to.NormalToGlobal(h.locNorm.dir)
+	h.globNorm.Normalize()
+	h.Surface = &to.Surface
+	return &h
+}
+
+// normalUV sets the outward normal and the mapping of the hit: u is the
+// angle around the y-axis, v the angle around the tube, 0 outside.
+func (to *Torus) normalUV(h *Hit) {
+	lp := h.locNorm.pt
+	phi := math.Atan2(-lp[Z], lp[X])
+	if phi < 0 {
+		phi += 2 * math.Pi
+	}
+	// radial direction, towards the nearest point of the major circle
+	radial := Vector3{math.Cos(phi), 0, -math.Sin(phi)}
+	rho := math.Hypot(lp[X], lp[Z])
+	theta := math.Atan2(lp[Y], rho-to.Major)
+	if theta < 0 {
+		theta += 2 * math.Pi
+	}
+	cos, sin := math.Cos(theta), math.Sin(theta)
+	h.locNorm.dir = radial.Mult(cos).Add(Vector3{0, sin, 0})
+	h.u, h.v = phi/(2*math.Pi), theta/(2*math.Pi)
+	dpdu := Vector3{2 * math.Pi * lp[Z], 0, -2 * math.Pi * lp[X]}
+	if rho < Epsilon {
+		dpdu = Vector3{0, 0, -2 * math.Pi}
+	}
+	dpdv := radial.Mult(-sin).Add(Vector3{0, cos, 0}).Mult(2 * math.Pi * to.Minor)
+	h.setTangents(&to.Transform, dpdu, dpdv)
+}
+
+// MinMax ...
+func (to *Torus) MinMax() (Point3, Point3) {
+	m := to.Major + to.Minor
+	return Point3{-m, -to.Minor, -m}, Point3{m, to.Minor, m}
+}
@@ -1,5 +1,7 @@
 package ray
 
+import "fmt"
+
 // Transformer ...
 type Transformer interface {
 	PointToLocal(Point3) Point3
@@ -47,6 +49,22 @@ func (t *Transform) RotateZ(a float64) {
 	t.direct = RotationZ(a).MulM(t.direct)
 }
 
+// Apply applies an arbitrary transformation matrix, which must be invertible.
+func (t *Transform) Apply(m Matrix4) error {
+	inv, ok := m.Inverse()
+	if !ok {
+		return fmt.Errorf("transform: matrix is not invertible: %v", m)
+	}
+	t.indirect = t.indirect.MulM(inv)
+	t.direct = m.MulM(t.direct)
+	return nil
+}
+
+// Matrix returns the matrix transforming local coords to global coords.
+func (t *Transform) Matrix() Matrix4 {
+	return t.direct
+}
+
 // RayToGlobal ...
 func (t *Transform) RayToGlobal(r Ray) Ray {
 	return Ray{pt: t.direct.MulP(r.pt), dir: t.direct.MulV(r.dir)}
@@ -57,6 +75,18 @@ func (t *Transform) RayToLocal(r Ray) Ray {
 	return Ray{pt: t.indirect.MulP(r.pt), dir: t.indirect.MulV(r.dir)}
 }
 
+// VectorToGlobal ...
+func (t *Transform) VectorToGlobal(v Vector3) Vector3 {
+	return t.direct.MulV(v)
+}
+
+// NormalToGlobal transforms a normal vector, using the transposed
+// inverse matrix so that it stays normal to the surface under non-uniform
+// scaling.
+func (t *Transform) NormalToGlobal(n Vector3) Vector3 {
+	return Transpose(t.indirect).MulV(n)
+}
+
 // PointToGlobal ...
 func (t *Transform) PointToGlobal(p Point3) Point3 {
 	return t.direct.MulP(p)
@@ -0,0 +1,138 @@
+package ray
+
+import "math"
+
+// Triangle is a triangle of 3 points given in local coords.
+// It is two-sided, the normal of the counter-clockwise side being the
+// outside (a, b, c are counter-clockwise when seen from outside).
+type Triangle struct {
+	Transform
+	Surface
+	name string
+	p    [3]Point3
+}
+
+// NewTriangle creates a triangle of points a, b, c
+func NewTriangle(a, b, c Point3) *Triangle {
+	return &Triangle{
+		Transform: IDTransform,
+		Surface:   DefaultSurface,
+		p:         [3]Point3{a, b, c},
+	}
+}
+
+// SetName ...
+func (tr *Triangle) SetName(name string) {
+	tr.name = "triangle:" + name
+}
+
+// Name returns the triangle's name
+func (tr *Triangle) Name() string {
+	return tr.name
+}
+
+// Surf ...
+func (tr *Triangle) Surf() *Surface {
+	return &tr.Surface
+}
+
+// Points returns the 3 points of the triangle in local coords
+func (tr *Triangle) Points() (Point3, Point3, Point3) {
+	return tr.p[0], tr.p[1], tr.p[2]
+}
+
+// Translate applies a translation to the triangle
+func (tr *Triangle) Translate(x, y, z float64) *Triangle {
+	tr.Transform.Translate(x, y, z)
+	return tr
+}
+
+// RotateX applies a rotation around x-axis to the triangle
+func (tr *Triangle) RotateX(x float64) *Triangle {
+	tr.Transform.RotateX(x)
+	return tr
+}
+
+// RotateY applies a rotation around y-axis to the triangle
+func (tr *Triangle) RotateY(y float64) *Triangle {
+	tr.Transform.RotateY(y)
+	return tr
+}
+
+// RotateZ applies a rotation around z-axis to the triangle
+func (tr *Triangle) RotateZ(z float64) *Triangle {
+	tr.Transform.RotateZ(z)
+	return tr
+}
+
+// Scale applies a scaling transform to the triangle
+func (tr *Triangle) Scale(x, y, z float64) *Triangle {
+	tr.Transform.Scale(x, y, z)
+	return tr
+}
+
+// Intersect ...
+func (tr *Triangle) Intersect(r Ray) *Hit {
+	locRay := tr.RayToLocal(r)
+	t, b1, b2, ok := intersectTriangle(locRay, tr.p[0], tr.p[1], tr.p[2])
+	if !ok {
+		return nil
+	}
+	var h Hit
+	h.globRay = r
+	h.locRay = locRay
+	e1 := NewVec(tr.p[0], tr.p[1])
+	e2 := NewVec(tr.p[0], tr.p[2])
+	h.locNorm.pt = Point3{
+		locRay.pt[X] + t*locRay.dir[X],
+		locRay.pt[Y] + t*locRay.dir[Y],
+		locRay.pt[Z] + t*locRay.dir[Z],
+	}
+	h.locNorm.dir = e1.Cross(e2)
+	h.u, h.v = b1, b2
+	h.setTangents(&tr.Transform, e1, e2)
+	if h.locNorm.dir.Dot(locRay.dir) > 0 {
+		h.locNorm.dir.Reverse()
+		h.inside = true
+	}
+	h.globNorm = tr.RayToGlobal(h.locNorm)
+	h.globNorm.Normalize()
+	h.Surface = &tr.Surface
+	return &h
+}
+
+// MinMax ...
+func (tr *Triangle) MinMax() (Point3, Point3) {
+	min := minPoint(minPoint(tr.p[0], tr.p[1]), tr.p[2])
+	max := maxPoint(maxPoint(tr.p[0], tr.p[1]), tr.p[2])
+	return min, max
+}
+
+// intersectTriangle returns the ray parameter and barycentric coords
+// of the intersection of the ray and triangle abc, if any (Möller-Trumbore).
+// The point is a + b1*(b-a) + b2*(c-a).
+func intersectTriangle(r Ray, a, b, c Point3) (float64, float64, float64, bool) {
+	e1 := NewVec(a, b)
+	e2 := NewVec(a, c)
+	p := r.dir.Cross(e2)
+	det := e1.Dot(p)
+	if math.Abs(det) < Epsilon*Epsilon {
+		return 0, 0, 0, false
+	}
+	inv := 1 / det
+	s := NewVec(a, r.pt)
+	b1 := s.Dot(p) * inv
+	if b1 < 0 || b1 > 1 {
+		return 0, 0, 0, false
+	}
+	q := s.Cross(e1)
+	b2 := r.dir.Dot(q) * inv
+	if b2 < 0 || b1+b2 > 1 {
+		return 0, 0, 0, false
+	}
+	t := e2.Dot(q) * inv
+	if t < Epsilon {
+		return 0, 0, 0, false
+	}
+	return t, b1, b2, true
+}